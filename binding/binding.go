@@ -7,7 +7,44 @@ type Binding interface {
 	Bind(*http.Request, any) error
 }
 
+// StreamBinding 可以逐个绑定数组元素的绑定器 目前只有 JSON
+type StreamBinding interface {
+	Binding
+	BindStream(r *http.Request, obj any, fn func(elem any) error) error
+}
+
+// mapper 只做绑定不做校验 用于 BindAll 中多个绑定器最后统一校验
+type mapper interface {
+	bind(*http.Request, any) error
+}
+
 var (
-	JSON = jsonBinding{}
-	XML  = xmlBinding{}
+	JSON          = jsonBinding{}
+	XML           = xmlBinding{}
+	Query         = queryBinding{}
+	Form          = formBinding{}
+	FormMultipart = formMultipartBinding{}
+	Uri           = uriBinding{}
+	Header        = headerBinding{}
+	Cookie        = cookieBinding{}
+	ProtoBuf      = protobufBinding{}
+	MsgPack       = msgpackBinding{}
+	YAML          = yamlBinding{}
+	TOML          = tomlBinding{}
 )
+
+// BindAll 依次执行绑定器 全部成功后只校验一次
+func BindAll(r *http.Request, obj any, bindings ...Binding) error {
+	for _, b := range bindings {
+		if m, ok := b.(mapper); ok {
+			if err := m.bind(r, obj); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.Bind(r, obj); err != nil {
+			return err
+		}
+	}
+	return validate(r.Context(), obj)
+}
@@ -0,0 +1,78 @@
+package binding
+
+import (
+	"bytes"
+	"github.com/BurntSushi/toml"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type codecUser struct {
+	Name string   `msgpack:"name" yaml:"name" toml:"name" validate:"required"`
+	Age  int      `msgpack:"age" yaml:"age" toml:"age"`
+	Tags []string `msgpack:"tags" yaml:"tags" toml:"tags"`
+}
+
+func TestBindCodecs(t *testing.T) {
+	encodeTOML := func(v any) ([]byte, error) {
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(v)
+		return buf.Bytes(), err
+	}
+	tests := []struct {
+		binding Binding
+		encode  func(v any) ([]byte, error)
+		invalid []byte
+	}{
+		{MsgPack, msgpack.Marshal, []byte{0xc1}},
+		{YAML, yaml.Marshal, []byte("name: [")},
+		{TOML, encodeTOML, []byte("name = ")},
+	}
+	user := codecUser{Name: "bob", Age: 30, Tags: []string{"a", "b"}}
+	for _, tt := range tests {
+		body, err := tt.encode(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got codecUser
+		if err = tt.binding.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), &got); err != nil || !reflect.DeepEqual(got, user) {
+			t.Errorf("%s: got %+v, %v", tt.binding.Name(), got, err)
+		}
+		// 解码后同样会校验
+		if body, err = tt.encode(codecUser{Age: 1}); err != nil {
+			t.Fatal(err)
+		}
+		var empty codecUser
+		err = tt.binding.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), &empty)
+		if _, ok := NewValidationError(err, nil); !ok {
+			t.Errorf("%s: expected validation error, got %v", tt.binding.Name(), err)
+		}
+		if err = tt.binding.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.invalid)), &empty); err == nil {
+			t.Errorf("%s: expected decode error", tt.binding.Name())
+		}
+	}
+}
+
+func TestBindProtoBuf(t *testing.T) {
+	body, err := proto.Marshal(wrapperspb.String("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got wrapperspb.StringValue
+	if err = ProtoBuf.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), &got); err != nil || got.GetValue() != "bob" {
+		t.Fatalf("got %q, %v", got.GetValue(), err)
+	}
+	var user codecUser
+	if err = ProtoBuf.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), &user); err == nil {
+		t.Fatal("expected error for non proto.Message")
+	}
+	if err = ProtoBuf.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte{0xff})), &got); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
@@ -0,0 +1,34 @@
+package binding
+
+import (
+	"net/http"
+	"net/url"
+)
+
+type cookieBinding struct {
+}
+
+func (cookieBinding) Name() string {
+	return "cookie"
+}
+
+// Bind cookie 绑定到 `cookie:"sid"` 标记的属性上
+func (b cookieBinding) Bind(r *http.Request, obj any) error {
+	if err := b.bind(r, obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
+
+func (cookieBinding) bind(r *http.Request, obj any) error {
+	values := make(map[string][]string)
+	for _, cookie := range r.Cookies() {
+		// Context.SetCookie 写入时做了 url.QueryEscape
+		value, err := url.QueryUnescape(cookie.Value)
+		if err != nil {
+			value = cookie.Value
+		}
+		values[cookie.Name] = append(values[cookie.Name], value)
+	}
+	return mapFormByTag(obj, values, nil, "cookie")
+}
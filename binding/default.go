@@ -0,0 +1,80 @@
+package binding
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEPROTOBUF          = "application/x-protobuf"
+	MIMEMSGPACK           = "application/x-msgpack"
+	MIMEMSGPACK2          = "application/msgpack"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMETOML              = "application/toml"
+)
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+var (
+	bindersMutex sync.RWMutex
+	binders      = map[string]Binding{
+		MIMEJSON:              JSON,
+		MIMEXML:               XML,
+		MIMEXML2:              XML,
+		MIMEPOSTForm:          Form,
+		MIMEMultipartPOSTForm: FormMultipart,
+		MIMEPROTOBUF:          ProtoBuf,
+		MIMEMSGPACK:           MsgPack,
+		MIMEMSGPACK2:          MsgPack,
+		MIMEYAML:              YAML,
+		MIMEYAML2:             YAML,
+		MIMETOML:              TOML,
+	}
+)
+
+// Register 注册 Content-Type 对应的绑定器 已存在时覆盖
+func Register(contentType string, b Binding) {
+	bindersMutex.Lock()
+	defer bindersMutex.Unlock()
+	binders[strings.ToLower(contentType)] = b
+}
+
+// Default 根据请求方法和 Content-Type 选择绑定器
+// GET/HEAD 请求绑定url参数，没有 Content-Type 时当作表单，找不到对应的绑定器返回 ErrUnsupportedMediaType
+func Default(method, contentType string) (Binding, error) {
+	if method == http.MethodGet || method == http.MethodHead {
+		return Query, nil
+	}
+	if contentType == "" {
+		return Form, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedMediaType
+	}
+	bindersMutex.RLock()
+	b, ok := binders[mediaType]
+	bindersMutex.RUnlock()
+	if ok {
+		return b, nil
+	}
+	// application/problem+json 这类结构化后缀
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSON, nil
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XML, nil
+	case strings.HasSuffix(mediaType, "+yaml"):
+		return YAML, nil
+	}
+	return nil, ErrUnsupportedMediaType
+}
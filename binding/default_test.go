@@ -0,0 +1,74 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type namedBinding struct {
+	name string
+}
+
+func (b namedBinding) Name() string {
+	return b.name
+}
+
+func (namedBinding) Bind(*http.Request, any) error {
+	return nil
+}
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		method, contentType string
+		want                Binding
+	}{
+		{http.MethodGet, MIMEJSON, Query},
+		{http.MethodHead, MIMEJSON, Query},
+		{http.MethodPost, "", Form},
+		{http.MethodPost, "application/json; charset=utf-8", JSON},
+		{http.MethodPut, "Application/JSON", JSON},
+		{http.MethodPost, MIMEMultipartPOSTForm + "; boundary=x", FormMultipart},
+		{http.MethodPost, "application/problem+json", JSON},
+		{http.MethodPost, "application/atom+xml", XML},
+		{http.MethodPost, "application/merge-patch+yaml", YAML},
+		{http.MethodPost, MIMEXML2, XML},
+		{http.MethodPost, MIMEMSGPACK2, MsgPack},
+		{http.MethodPost, MIMETOML, TOML},
+	}
+	for _, tt := range tests {
+		got, err := Default(tt.method, tt.contentType)
+		if err != nil || got != tt.want {
+			t.Errorf("Default(%s, %q) = %v, %v, want %s", tt.method, tt.contentType, got, err, tt.want.Name())
+		}
+	}
+	for _, contentType := range []string{"text/csv", "application/x-unknown", "invalid;;"} {
+		if _, err := Default(http.MethodPost, contentType); !errors.Is(err, ErrUnsupportedMediaType) {
+			t.Errorf("Default(POST, %q) err = %v", contentType, err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	csv := namedBinding{name: "csv"}
+	Register("Text/CSV", csv)
+	defer func() {
+		bindersMutex.Lock()
+		delete(binders, "text/csv")
+		bindersMutex.Unlock()
+	}()
+	if got, err := Default(http.MethodPost, "text/csv; charset=utf-8"); err != nil || got != csv {
+		t.Fatalf("Default = %v, %v", got, err)
+	}
+	// 覆盖内置的绑定器
+	custom := namedBinding{name: "custom-json"}
+	Register(MIMEJSON, custom)
+	defer Register(MIMEJSON, JSON)
+	if got, _ := Default(http.MethodPost, MIMEJSON); got != custom {
+		t.Fatalf("Default = %v, want the registered binding", got)
+	}
+	// 结构化后缀不受影响
+	if got, _ := Default(http.MethodPost, "application/problem+json"); got != JSON {
+		t.Fatalf("Default = %v, want JSON", got)
+	}
+}
@@ -0,0 +1,118 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
+// FieldError 一个属性的校验错误
+type FieldError struct {
+	Field   string `json:"field"` // json路径 比如 address.city items[0].name
+	Rule    string `json:"rule"`  // 没有通过的规则 比如 required min
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"` // 翻译后的提示
+}
+
+// ValidationError 结构化的校验错误 可以直接作为响应返回给客户端
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	for i, fe := range e.Errors {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(fe.Field)
+		b.WriteString(": ")
+		b.WriteString(fe.Message)
+	}
+	return b.String()
+}
+
+// NewValidationError 把校验器返回的错误转换成 ValidationError trans 为空时使用英文
+// 支持 validator.ValidationErrors SliceValidationError MissingFieldsError 和 ElementError 其它错误返回 false
+func NewValidationError(err error, trans ut.Translator) (*ValidationError, bool) {
+	if trans == nil {
+		trans = Translator()
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve, true
+	}
+	fields, ok := fieldErrors(err, trans, "")
+	if !ok {
+		return nil, false
+	}
+	return &ValidationError{Errors: fields}, true
+}
+
+func fieldErrors(err error, trans ut.Translator, prefix string) ([]FieldError, bool) {
+	var (
+		validationErrors validator.ValidationErrors
+		sliceErrors      SliceValidationError
+		missing          MissingFieldsError
+		elementError     *ElementError
+	)
+	switch {
+	case errors.As(err, &elementError):
+		return fieldErrors(elementError.Err, trans, prefix+"["+strconv.Itoa(elementError.Index)+"].")
+	case errors.As(err, &validationErrors):
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{
+				Field:   prefix + fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: translate(fe, trans),
+			})
+		}
+		return fields, true
+	case errors.As(err, &sliceErrors):
+		var fields []FieldError
+		for i, e := range sliceErrors {
+			if e == nil {
+				continue
+			}
+			elem, ok := fieldErrors(e, trans, prefix+"["+strconv.Itoa(i)+"].")
+			if !ok {
+				return nil, false
+			}
+			fields = append(fields, elem...)
+		}
+		return fields, true
+	case errors.As(err, &missing):
+		fields := make([]FieldError, 0, len(missing))
+		for _, path := range missing {
+			msg, e := trans.T("required", path)
+			if e != nil {
+				msg = fmt.Sprintf("%s is a required field", path)
+			}
+			fields = append(fields, FieldError{Field: prefix + path, Rule: "required", Message: msg})
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// translate 没有注册提示的规则(比如自定义规则)不使用校验器原始的错误信息
+func translate(fe validator.FieldError, trans ut.Translator) string {
+	if msg := fe.Translate(trans); msg != fe.Error() {
+		return msg
+	}
+	return fmt.Sprintf("%s failed on the '%s' rule", fe.Field(), fe.Tag())
+}
+
+// fieldPath 去掉命名空间开头的结构体名 User.address.city -> address.city
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
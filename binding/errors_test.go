@@ -0,0 +1,103 @@
+package binding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name    string          `json:"name" validate:"required"`
+	Age     int             `json:"age" validate:"min=18"`
+	Address validateAddress `json:"address"`
+}
+
+func TestNewValidationError(t *testing.T) {
+	err := Validator.ValidateStruct(&validateUser{Age: 3})
+	cases := map[string][]FieldError{
+		"en": {
+			{Field: "name", Rule: "required", Message: "name is a required field"},
+			{Field: "age", Rule: "min", Param: "18", Message: "age must be 18 or greater"},
+			{Field: "address.city", Rule: "required", Message: "city is a required field"},
+		},
+		"zh": {
+			{Field: "name", Rule: "required", Message: "name为必填字段"},
+			{Field: "age", Rule: "min", Param: "18", Message: "age最小只能为18"},
+			{Field: "address.city", Rule: "required", Message: "city为必填字段"},
+		},
+	}
+	for locale, want := range cases {
+		ve, ok := NewValidationError(err, Translator(locale))
+		if !ok {
+			t.Fatalf("%s: not a validation error: %v", locale, err)
+		}
+		if !reflect.DeepEqual(ve.Errors, want) {
+			t.Errorf("%s: got %+v\nwant %+v", locale, ve.Errors, want)
+		}
+	}
+	// 不支持的语言使用英文
+	if ve, _ := NewValidationError(err, Translator("fr_FR", "fr")); ve.Errors[0].Message != "name is a required field" {
+		t.Fatalf("fallback: %q", ve.Errors[0].Message)
+	}
+	// 其它错误不转换
+	if _, ok := NewValidationError(errors.New("bad json"), nil); ok {
+		t.Fatal("plain error converted")
+	}
+}
+
+func TestNewValidationErrorPaths(t *testing.T) {
+	slice := Validator.ValidateStruct([]validateUser{{Name: "a", Age: 20, Address: validateAddress{City: "x"}}, {Age: 20, Address: validateAddress{City: "x"}}})
+	missing := MissingFieldsError{"address.city"}
+	cases := []struct {
+		err  error
+		want []string
+	}{
+		{slice, []string{"[1].name"}},
+		{&ElementError{Index: 2, Err: missing}, []string{"[2].address.city"}},
+		{missing, []string{"address.city"}},
+	}
+	for _, tc := range cases {
+		ve, ok := NewValidationError(tc.err, Translator("zh"))
+		if !ok {
+			t.Fatalf("%v: not a validation error", tc.err)
+		}
+		var got []string
+		for _, fe := range ve.Errors {
+			got = append(got, fe.Field)
+			if fe.Message == "" || fe.Rule == "" {
+				t.Errorf("%s: empty rule or message: %+v", fe.Field, fe)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: fields = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+	if ve, _ := NewValidationError(missing, Translator("zh")); ve.Errors[0].Message != "address.city为必填字段" {
+		t.Fatalf("missing field translation: %q", ve.Errors[0].Message)
+	}
+}
+
+func TestValidationErrorFormatting(t *testing.T) {
+	ve := &ValidationError{Errors: []FieldError{
+		{Field: "name", Message: "name is a required field"},
+		{Field: "age", Message: "age must be 18 or greater"},
+	}}
+	if got := ve.Error(); got != "name: name is a required field; age: age must be 18 or greater" {
+		t.Fatalf("ValidationError.Error() = %q", got)
+	}
+	// 没有错误的元素不输出
+	slice := SliceValidationError{nil, errors.New("a"), nil, errors.New("b")}
+	if got := slice.Error(); got != "[1]: a\n[3]: b" {
+		t.Fatalf("SliceValidationError.Error() = %q", got)
+	}
+	if got := (MissingFieldsError{"a", "b.c"}).Error(); got != "required fields missing: a, b.c" {
+		t.Fatalf("MissingFieldsError.Error() = %q", got)
+	}
+	if got := (&ElementError{Index: 3, Err: errors.New("x")}).Error(); got != "[3]: x" {
+		t.Fatalf("ElementError.Error() = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+)
+
+// DefaultMultipartMemory MaxMemory 为0时解析multipart表单放在内存中的最大字节数 超出部分写入临时文件
+const DefaultMultipartMemory = 32 << 20 // 32M
+
+type queryBinding struct {
+}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+// Bind 把 url 上的参数绑定到 `form:"..."` 标记的属性上
+func (b queryBinding) Bind(r *http.Request, obj any) error {
+	if err := b.bind(r, obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
+
+func (queryBinding) bind(r *http.Request, obj any) error {
+	return mapForm(obj, r.URL.Query(), nil)
+}
+
+type formBinding struct {
+	MaxMemory int64 // 解析multipart表单时放在内存中的最大字节数 0 使用 DefaultMultipartMemory
+}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+// Bind url参数和 x-www-form-urlencoded/multipart 表单中的普通字段 表单中的值优先
+func (b formBinding) Bind(r *http.Request, obj any) error {
+	if err := b.bind(r, obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
+
+func (b formBinding) bind(r *http.Request, obj any) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if err := r.ParseMultipartForm(maxMemory(b.MaxMemory)); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	return mapForm(obj, r.Form, nil)
+}
+
+type formMultipartBinding struct {
+	MaxMemory int64 // 同 formBinding.MaxMemory
+}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+// Bind multipart 表单 除了普通字段还会绑定 *multipart.FileHeader 和 []*multipart.FileHeader
+func (b formMultipartBinding) Bind(r *http.Request, obj any) error {
+	if err := b.bind(r, obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
+
+func (b formMultipartBinding) bind(r *http.Request, obj any) error {
+	if err := r.ParseMultipartForm(maxMemory(b.MaxMemory)); err != nil {
+		return err
+	}
+	return mapForm(obj, r.MultipartForm.Value, r.MultipartForm.File)
+}
+
+func maxMemory(n int64) int64 {
+	if n > 0 {
+		return n
+	}
+	return DefaultMultipartMemory
+}
@@ -0,0 +1,526 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// MaxFormSliceLen 结构体切片 items[0][name] 最多的元素个数 下标超出时返回 ErrFormSliceTooLong
+// 下标来自客户端 不限制的话一个很大的下标就能让服务端创建大量元素
+var MaxFormSliceLen = 1000
+
+var ErrFormSliceTooLong = errors.New("too many elements in form slice")
+
+// formSource 表单类数据的来源 tag 决定读取结构体中的哪个标签
+type formSource struct {
+	values    map[string][]string
+	files     map[string][]*multipart.FileHeader
+	tag       string
+	normalize func(string) string // 查找前对 key 的处理 比如 header 需要转成规范格式
+}
+
+// tagOptions `form:"name,default=1"`
+type tagOptions struct {
+	defaultValue string
+	hasDefault   bool
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	name, rest, _ := strings.Cut(tag, ",")
+	var opt tagOptions
+	for _, o := range strings.Split(rest, ",") {
+		if strings.HasPrefix(o, "default=") {
+			opt.defaultValue = strings.TrimPrefix(o, "default=")
+			opt.hasDefault = true
+		}
+	}
+	return name, opt
+}
+
+func mapForm(obj any, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	return mapFormByTag(obj, values, files, "form")
+}
+
+// mapFormByTag 嵌套结构体使用 user[name] 或 user.name，map 使用 usr[id]，结构体切片使用 items[0][name]
+func mapFormByTag(obj any, values map[string][]string, files map[string][]*multipart.FileHeader, tag string) error {
+	return (&formSource{values: values, files: files, tag: tag}).bind(obj)
+}
+
+func (s *formSource) bind(obj any) error {
+	valueOf := reflect.ValueOf(obj)
+	if valueOf.Kind() != reflect.Pointer || valueOf.IsNil() {
+		return errors.New("This argument must have a pointer type ")
+	}
+	v := valueOf.Elem()
+	switch v.Kind() {
+	case reflect.Map:
+		return s.mapWholeMap(v)
+	case reflect.Struct:
+		_, err := s.mapStruct(v, "")
+		return err
+	}
+	return fmt.Errorf("cannot bind %s into %s", s.tag, v.Type())
+}
+
+func childKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "[" + name + "]"
+}
+
+var dottedReplacer = strings.NewReplacer("][", ".", "[", ".", "]", "")
+
+// dotted a[b][c] -> a.b.c
+func dotted(key string) string {
+	return dottedReplacer.Replace(key)
+}
+
+func (s *formSource) lookup(key string) ([]string, bool) {
+	if s.normalize != nil {
+		key = s.normalize(key)
+	}
+	if vals, ok := s.values[key]; ok {
+		return vals, true
+	}
+	if vals, ok := s.values[key+"[]"]; ok {
+		return vals, true
+	}
+	if alt := dotted(key); alt != key {
+		vals, ok := s.values[alt]
+		return vals, ok
+	}
+	return nil, false
+}
+
+// has 是否存在以 key 开头的参数 用于决定是否创建指针和结构体切片的元素
+func (s *formSource) has(key string) bool {
+	if s.normalize != nil {
+		key = s.normalize(key)
+	}
+	alt := dotted(key)
+	for k := range s.values {
+		if k == key || strings.HasPrefix(k, key+"[") || k == alt || strings.HasPrefix(k, alt+".") {
+			return true
+		}
+	}
+	for k := range s.files {
+		if k == key || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *formSource) mapStruct(v reflect.Value, prefix string) (bool, error) {
+	t := v.Type()
+	isSet := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(s.tag)
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, opt := parseTag(tag)
+		fieldValue := v.Field(i)
+		// 没有标签的嵌入结构体展开到当前层级
+		if field.Anonymous && name == "" {
+			if !field.IsExported() && field.Type.Kind() != reflect.Struct {
+				continue
+			}
+			ok, err := s.mapEmbedded(fieldValue, prefix)
+			if err != nil {
+				return false, err
+			}
+			isSet = isSet || ok
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		ok, err := s.mapValue(fieldValue, field, childKey(prefix, name), opt)
+		if err != nil {
+			return false, err
+		}
+		isSet = isSet || ok
+	}
+	return isSet, nil
+}
+
+func (s *formSource) mapEmbedded(v reflect.Value, prefix string) (bool, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.Type().Elem().Kind() != reflect.Struct || !v.CanSet() {
+			return false, nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		ok, err := s.mapStruct(elem.Elem(), prefix)
+		if ok {
+			v.Set(elem)
+		}
+		return ok, err
+	}
+	if v.Kind() != reflect.Struct {
+		return false, nil
+	}
+	return s.mapStruct(v, prefix)
+}
+
+// isScalar 把 time.Time 和实现了 TextUnmarshaler 的类型当作单个值处理
+func isScalar(t reflect.Type) bool {
+	if t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer:
+		return false
+	}
+	return true
+}
+
+func (s *formSource) mapValue(v reflect.Value, field reflect.StructField, key string, opt tagOptions) (bool, error) {
+	switch v.Type() {
+	case fileHeaderType:
+		if files := s.files[key]; len(files) > 0 {
+			v.Set(reflect.ValueOf(files[0]))
+			return true, nil
+		}
+		return false, nil
+	case fileHeaderSliceType:
+		if files := s.files[key]; len(files) > 0 {
+			v.Set(reflect.ValueOf(files))
+			return true, nil
+		}
+		return false, nil
+	}
+	if isScalar(v.Type()) {
+		vals, ok := s.lookup(key)
+		if !ok || len(vals) == 0 {
+			if !opt.hasDefault {
+				return false, nil
+			}
+			vals = []string{opt.defaultValue}
+		}
+		return true, setScalar(v, vals[0], field, key)
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !s.has(key) && !opt.hasDefault {
+			return false, nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		ok, err := s.mapValue(elem.Elem(), field, key, opt)
+		if ok {
+			v.Set(elem)
+		}
+		return ok, err
+	case reflect.Struct:
+		return s.mapStruct(v, key)
+	case reflect.Map:
+		return s.mapMap(v, field, key)
+	case reflect.Slice, reflect.Array:
+		return s.mapSlice(v, field, key, opt)
+	}
+	return false, fmt.Errorf("unsupported type %s for field %s", v.Type(), field.Name)
+}
+
+func (s *formSource) mapSlice(v reflect.Value, field reflect.StructField, key string, opt tagOptions) (bool, error) {
+	elemType := v.Type().Elem()
+	if !isScalar(elemType) {
+		// 结构体切片 items[0][name]=a&items[1][name]=b
+		if v.Kind() != reflect.Slice {
+			return false, fmt.Errorf("unsupported type %s for field %s", v.Type(), field.Name)
+		}
+		indexes, err := s.sliceIndexes(key)
+		if err != nil {
+			return false, fmt.Errorf("field [%s]: %w", key, err)
+		}
+		// 下标从0开始连续 遇到缺少的下标结束
+		slice := reflect.MakeSlice(v.Type(), 0, 0)
+		for i := 0; indexes[i]; i++ {
+			elem := reflect.New(elemType).Elem()
+			if _, err := s.mapValue(elem, field, childKey(key, strconv.Itoa(i)), tagOptions{}); err != nil {
+				return false, err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		if slice.Len() == 0 {
+			return false, nil
+		}
+		v.Set(slice)
+		return true, nil
+	}
+	vals, ok := s.lookup(key)
+	if !ok || len(vals) == 0 {
+		if !opt.hasDefault {
+			return false, nil
+		}
+		vals = strings.Split(opt.defaultValue, ";")
+	}
+	if v.Kind() == reflect.Array {
+		if len(vals) != v.Len() {
+			return false, fmt.Errorf("%q is not valid value for %s", vals, v.Type())
+		}
+		for i, val := range vals {
+			if err := setScalar(v.Index(i), val, field, key); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+	for i, val := range vals {
+		if err := setScalar(slice.Index(i), val, field, key); err != nil {
+			return false, err
+		}
+	}
+	v.Set(slice)
+	return true, nil
+}
+
+// sliceIndexes 一次遍历取出 key[0] 和 key.0 形式的参数中出现的下标
+func (s *formSource) sliceIndexes(key string) (map[int]bool, error) {
+	if s.normalize != nil {
+		key = s.normalize(key)
+	}
+	alt := dotted(key)
+	indexes := make(map[int]bool)
+	add := func(index string) error {
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 {
+			return nil
+		}
+		if i >= MaxFormSliceLen {
+			return ErrFormSliceTooLong
+		}
+		indexes[i] = true
+		return nil
+	}
+	collect := func(k string) error {
+		if strings.HasPrefix(k, key+"[") {
+			rest := k[len(key)+1:]
+			if j := strings.IndexByte(rest, ']'); j >= 1 {
+				return add(rest[:j])
+			}
+		} else if strings.HasPrefix(k, alt+".") {
+			rest := k[len(alt)+1:]
+			if j := strings.IndexByte(rest, '.'); j >= 0 {
+				rest = rest[:j]
+			}
+			return add(rest)
+		}
+		return nil
+	}
+	for k := range s.values {
+		if err := collect(k); err != nil {
+			return nil, err
+		}
+	}
+	for k := range s.files {
+		if err := collect(k); err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+// mapKeys 取出 key[sub] 和 key.sub 形式的参数 与 Context.get 的规则一致
+func (s *formSource) mapKeys(key string) map[string][]string {
+	result := make(map[string][]string)
+	for k, vals := range s.values {
+		if strings.HasPrefix(k, key+"[") {
+			rest := k[len(key)+1:]
+			if j := strings.IndexByte(rest, ']'); j >= 1 {
+				result[rest[:j]] = vals
+			}
+		} else if key != "" && strings.HasPrefix(k, key+".") {
+			result[k[len(key)+1:]] = vals
+		}
+	}
+	return result
+}
+
+func (s *formSource) mapMap(v reflect.Value, field reflect.StructField, key string) (bool, error) {
+	if v.Type().Key().Kind() != reflect.String {
+		return false, fmt.Errorf("unsupported map key type %s for field %s", v.Type().Key(), field.Name)
+	}
+	entries := s.mapKeys(key)
+	if len(entries) == 0 {
+		return false, nil
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	elemType := v.Type().Elem()
+	for k, vals := range entries {
+		elem := reflect.New(elemType).Elem()
+		if elemType.Kind() == reflect.Slice && isScalar(elemType.Elem()) {
+			slice := reflect.MakeSlice(elemType, len(vals), len(vals))
+			for i, val := range vals {
+				if err := setScalar(slice.Index(i), val, field, key); err != nil {
+					return false, err
+				}
+			}
+			elem.Set(slice)
+		} else if isScalar(elemType) {
+			if err := setScalar(elem, vals[0], field, key); err != nil {
+				return false, err
+			}
+		} else {
+			return false, fmt.Errorf("unsupported map value type %s for field %s", elemType, field.Name)
+		}
+		v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+	}
+	return true, nil
+}
+
+// mapWholeMap 直接绑定到 map[string]string 或 map[string][]string
+func (s *formSource) mapWholeMap(v reflect.Value) error {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot bind form into %s", t)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	for k, vals := range s.values {
+		switch {
+		case t.Elem().Kind() == reflect.String && len(vals) > 0:
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), reflect.ValueOf(vals[0]).Convert(t.Elem()))
+		case t.Elem() == reflect.TypeOf([]string(nil)):
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), reflect.ValueOf(vals))
+		default:
+			return fmt.Errorf("cannot bind form into %s", t)
+		}
+	}
+	return nil
+}
+
+// setScalar 把字符串转换成属性的类型 空字符串设置为零值
+func setScalar(v reflect.Value, val string, field reflect.StructField, key string) error {
+	if err := setWithProperType(v, val, field); err != nil {
+		return fmt.Errorf("field [%s]: %w", key, err)
+	}
+	return nil
+}
+
+func setWithProperType(v reflect.Value, val string, field reflect.StructField) error {
+	switch v.Type() {
+	case timeType:
+		return setTime(v, val, field)
+	case durationType:
+		if val == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+		return nil
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(val))
+			return nil
+		}
+	}
+	if val == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// setTime 支持 `time_format:"2006-01-02"` 默认 RFC3339，unix/unixmilli/unixnano 表示时间戳
+// `time_location:"Asia/Shanghai"` 指定时区 默认 time.Local
+func setTime(v reflect.Value, val string, field reflect.StructField) error {
+	if val == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	format := field.Tag.Get("time_format")
+	switch format {
+	case "unix", "unixmilli", "unixnano":
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		var t time.Time
+		switch format {
+		case "unix":
+			t = time.Unix(n, 0)
+		case "unixmilli":
+			t = time.UnixMilli(n)
+		default:
+			t = time.Unix(0, n)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case "":
+		format = time.RFC3339
+	}
+	loc := time.Local
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return err
+		}
+		loc = l
+	}
+	t, err := time.ParseInLocation(format, val, loc)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -0,0 +1,184 @@
+package binding
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type formAddress struct {
+	City string `form:"city"`
+	Zip  *int   `form:"zip"`
+}
+
+type formItem struct {
+	Name  string `form:"name"`
+	Count int    `form:"count,default=1"`
+}
+
+type formUser struct {
+	Name     string                  `form:"name"`
+	Address  formAddress             `form:"address"`
+	Backup   *formAddress            `form:"backup"`
+	Ids      map[string]int          `form:"usr"`
+	Tags     map[string][]string     `form:"tags"`
+	Items    []formItem              `form:"items"`
+	Birthday time.Time               `form:"birthday" time_format:"2006-01-02" time_location:"UTC"`
+	Created  time.Time               `form:"created" time_format:"unix"`
+	Timeout  time.Duration           `form:"timeout"`
+	Avatar   *multipart.FileHeader   `form:"avatar"`
+	Photos   []*multipart.FileHeader `form:"photos"`
+}
+
+func TestMapFormNested(t *testing.T) {
+	values := map[string][]string{
+		"name":            {"bob"},
+		"address[city]":   {"Shanghai"},
+		"address[zip]":    {"200000"},
+		"backup.city":     {"Beijing"},
+		"usr[a]":          {"1"},
+		"usr[b]":          {"2"},
+		"tags[color]":     {"red", "blue"},
+		"items[0][name]":  {"apple"},
+		"items.1.name":    {"pear"},
+		"items[1][count]": {"3"},
+		"birthday":        {"2000-01-02"},
+		"created":         {"1600000000"},
+		"timeout":         {"1m30s"},
+	}
+	avatar := &multipart.FileHeader{Filename: "a.png"}
+	photos := []*multipart.FileHeader{{Filename: "1.png"}, {Filename: "2.png"}}
+	files := map[string][]*multipart.FileHeader{"avatar": {avatar}, "photos": photos}
+	var u formUser
+	if err := mapForm(&u, values, files); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "bob" || u.Address.City != "Shanghai" || u.Address.Zip == nil || *u.Address.Zip != 200000 {
+		t.Fatalf("nested struct: %+v", u.Address)
+	}
+	// 有参数时才创建指针
+	if u.Backup == nil || u.Backup.City != "Beijing" || u.Backup.Zip != nil {
+		t.Fatalf("pointer struct: %+v", u.Backup)
+	}
+	if !reflect.DeepEqual(u.Ids, map[string]int{"a": 1, "b": 2}) {
+		t.Fatalf("map: %v", u.Ids)
+	}
+	if !reflect.DeepEqual(u.Tags, map[string][]string{"color": {"red", "blue"}}) {
+		t.Fatalf("map of slices: %v", u.Tags)
+	}
+	if !reflect.DeepEqual(u.Items, []formItem{{"apple", 1}, {"pear", 3}}) {
+		t.Fatalf("struct slice: %+v", u.Items)
+	}
+	if !u.Birthday.Equal(time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("time_format: %v", u.Birthday)
+	}
+	if u.Created.Unix() != 1600000000 {
+		t.Fatalf("unix time: %v", u.Created)
+	}
+	if u.Timeout != 90*time.Second {
+		t.Fatalf("duration: %v", u.Timeout)
+	}
+	if u.Avatar != avatar || !reflect.DeepEqual(u.Photos, photos) {
+		t.Fatalf("files: %v %v", u.Avatar, u.Photos)
+	}
+}
+
+func TestMapFormEmpty(t *testing.T) {
+	var u formUser
+	if err := mapForm(&u, map[string][]string{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if u.Backup != nil || u.Ids != nil || u.Items != nil || !u.Birthday.IsZero() || u.Avatar != nil {
+		t.Fatalf("unexpected values: %+v", u)
+	}
+}
+
+func TestMapFormSliceGap(t *testing.T) {
+	// 下标不连续时只取前面连续的部分
+	values := map[string][]string{"items[0][name]": {"a"}, "items[2][name]": {"c"}}
+	var u formUser
+	if err := mapForm(&u, values, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Items) != 1 || u.Items[0].Name != "a" {
+		t.Fatalf("items: %+v", u.Items)
+	}
+}
+
+func TestMapFormSliceTooLong(t *testing.T) {
+	values := map[string][]string{"items[" + strconv.Itoa(MaxFormSliceLen) + "][name]": {"x"}}
+	var u formUser
+	if err := mapForm(&u, values, nil); !errors.Is(err, ErrFormSliceTooLong) {
+		t.Fatalf("err = %v, want ErrFormSliceTooLong", err)
+	}
+}
+
+func BenchmarkMapFormStructSlice(b *testing.B) {
+	values := make(map[string][]string)
+	for i := 0; i < MaxFormSliceLen; i++ {
+		values["items["+strconv.Itoa(i)+"][name]"] = []string{"x"}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var u formUser
+		if err := mapForm(&u, values, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type requestParams struct {
+	Id      int      `uri:"id" validate:"required"`
+	Tenant  string   `header:"x-tenant"`
+	Accepts []string `header:"Accept"`
+	Session string   `cookie:"sid"`
+	Lang    string   `cookie:"lang,default=en"`
+}
+
+func TestBindUriHeaderCookie(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/user/7", nil)
+	r = WithParams(r, map[string]string{"id": "7"})
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+	r.AddCookie(&http.Cookie{Name: "sid", Value: url.QueryEscape("a b")})
+
+	var uri requestParams
+	if err := Uri.Bind(r, &uri); err != nil || uri.Id != 7 || uri.Tenant != "" {
+		t.Fatalf("Uri: %+v %v", uri, err)
+	}
+	var header requestParams
+	// 名字不区分大小写 没有路径参数时 required 校验失败
+	if err := Header.Bind(r, &header); err == nil {
+		t.Fatal("Header: expected required error for id")
+	}
+	if header.Tenant != "acme" || !reflect.DeepEqual(header.Accepts, []string{"text/html", "application/json"}) {
+		t.Fatalf("Header: %+v", header)
+	}
+	var cookie requestParams
+	_ = Cookie.Bind(r, &cookie)
+	if cookie.Session != "a b" || cookie.Lang != "en" {
+		t.Fatalf("Cookie: %+v", cookie)
+	}
+
+	var all requestParams
+	if err := BindAll(r, &all, Uri, Header, Cookie); err != nil {
+		t.Fatal(err)
+	}
+	want := requestParams{Id: 7, Tenant: "acme", Accepts: []string{"text/html", "application/json"}, Session: "a b", Lang: "en"}
+	if !reflect.DeepEqual(all, want) {
+		t.Fatalf("BindAll = %+v, want %+v", all, want)
+	}
+
+	// 路径参数类型错误
+	var bad requestParams
+	if err := Uri.Bind(WithParams(r, map[string]string{"id": "x"}), &bad); err == nil {
+		t.Fatal("Uri: expected error for non-numeric id")
+	}
+}
@@ -0,0 +1,30 @@
+package binding
+
+import (
+	"net/http"
+	"net/textproto"
+)
+
+type headerBinding struct {
+}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+// Bind 请求头绑定到 `header:"X-Tenant"` 标记的属性上 名字不区分大小写
+func (b headerBinding) Bind(r *http.Request, obj any) error {
+	if err := b.bind(r, obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
+
+func (headerBinding) bind(r *http.Request, obj any) error {
+	s := &formSource{
+		values:    r.Header, // http.Header 的 key 已经是规范格式
+		tag:       "header",
+		normalize: textproto.CanonicalMIMEHeaderKey,
+	}
+	return s.bind(obj)
+}
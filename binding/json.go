@@ -1,130 +1,122 @@
 package binding
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
+	"gitbuh.com/spxzx/spxgo/codec"
+	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 )
 
 type jsonBinding struct {
 	IsValidate            bool
 	DisallowUnknownFields bool
+	UseNumber             bool // 数字解码到 any 时使用 json.Number 而不是 float64 大整数不会丢失精度
 }
 
 func (jsonBinding) Name() string {
 	return "json"
 }
 
-// Bind JSON绑定器
+// Bind JSON绑定器 解码使用 codec.JSON
 func (b jsonBinding) Bind(r *http.Request, obj any) error {
 	body := r.Body // 传参的内容放在 http.Request.Body 中
 	if body == nil {
 		return errors.New("invalid request")
 	}
-	decoder := json.NewDecoder(body) // ** 解码器中含有body中的内容 **
-	if b.DisallowUnknownFields {
-		decoder.DisallowUnknownFields()
-	}
-	if b.IsValidate {
-		if err := validateParam(obj, decoder); err != nil {
-			return err
-		}
-	} else {
-		err := decoder.Decode(obj)
-		if err != nil {
+	if !b.IsValidate {
+		if err := b.newDecoder(body).Decode(obj); err != nil {
 			return err
 		}
+		return validate(r.Context(), obj)
 	}
-	return validate(obj)
+	// 只读一遍 解码的同时检查 `spxgo:"required"` 的属性是否都传了
+	return b.decodeElement(r, tokenDecoder(body), obj)
 }
 
-// ** 好好学习反射 **
-func validateParam(obj any, decoder *json.Decoder) error {
-	// 反射
-	valueOf := reflect.ValueOf(obj)
-	// 判断其是否为指针类型
-	if valueOf.Kind() != reflect.Pointer {
-		return errors.New("This argument must have a pointer type ")
+// tokenDecoder 读取文档结构的解码器 codec.JSON 的解码器不能逐个读取 token 时使用 encoding/json
+func tokenDecoder(body io.Reader) codec.JSONTokenDecoder {
+	if decoder, ok := codec.JSON.NewDecoder(body).(codec.JSONTokenDecoder); ok {
+		return decoder
 	}
-	temp := valueOf.Elem().Interface()
-	vOf := reflect.ValueOf(temp)
-	// obj 类型判断后才能进行解析操作
-	switch vOf.Kind() {
-	case reflect.Struct:
-		return checkParamDecoder(vOf, obj, decoder)
-	case reflect.Slice, reflect.Array:
-		elem := vOf.Type().Elem()
-		if elem.Kind() == reflect.Struct {
-			return checkParamSliceDecoder(elem, obj, decoder)
-		} else if elem.Kind() == reflect.Pointer {
-			return checkParamSliceDecoder(elem.Elem(), obj, decoder)
-		}
-	default:
-		if err := decoder.Decode(obj); err != nil {
-			return err
-		}
-	}
-	return nil
+	return json.NewDecoder(body)
 }
 
-func checkParamDecoder(vOf reflect.Value, obj any, decoder *json.Decoder) error {
-	// 解析为map，然后根据map中的key进行对比
-	mapData := make(map[string]any)
-	// 因为解码器中带有body内容，所以可以将body解析成对应map
-	if err := decoder.Decode(&mapData); err != nil {
-		log.Println(err)
-		return err
+func (b jsonBinding) newDecoder(body io.Reader) codec.JSONDecoder {
+	decoder := codec.JSON.NewDecoder(body) // ** 解码器中含有body中的内容 **
+	if b.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if b.UseNumber {
+		decoder.UseNumber()
 	}
-	for i := 0; i < vOf.NumField(); i++ {
-		field := vOf.Type().Field(i)
-		name := field.Name                // post form 中的 json 属性名
-		jsonName := field.Tag.Get("json") // 寻找结构体中`json:`的属性名
-		required := field.Tag.Get("spxgo")
-		val := mapData[jsonName]
-		if val == nil && required == "required" {
-			return errors.New(
-				fmt.Sprintf("filed [%s] is requird", name))
+	return decoder
+}
+
+// decodeElement 从 decoder 读取下一个值解码到 obj 并校验 IsValidate 时检查必填属性
+func (b jsonBinding) decodeElement(r *http.Request, decoder codec.JSONTokenDecoder, obj any) error {
+	if b.IsValidate {
+		if err := b.decodeRequired(decoder, obj); err != nil {
+			return err
 		}
+		return validate(r.Context(), obj)
 	}
-	// 因为decoder.Decode 不能重复执行 下面的操作相当于decoder.Decode(obj)
-	b, err := json.Marshal(mapData) // 将数据编码成json字符串
-	if err != nil {
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
 		return err
 	}
-	if err = json.Unmarshal(b, obj); err != nil { // 将json字符串解码到相应的数据结构 obj相当于是一个数据结构模型
+	if err := b.newDecoder(bytes.NewReader(raw)).Decode(obj); err != nil {
 		return err
 	}
-	return err
+	return validate(r.Context(), obj)
 }
 
-func checkParamSliceDecoder(elem reflect.Type, obj any, decoder *json.Decoder) error {
-	mapData := make([]map[string]any, 0)
-	if err := decoder.Decode(&mapData); err != nil {
-		return err
-	}
-	if len(mapData) <= 0 {
-		return nil
+// ElementError 流式绑定中第 Index 个元素的错误
+type ElementError struct {
+	Index int
+	Err   error
+}
+
+func (e *ElementError) Error() string {
+	return "[" + strconv.Itoa(e.Index) + "]: " + e.Err.Error()
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// BindStream 请求体为json数组时逐个解码元素 不把整个数组读入内存
+// obj 为元素的指针 每个元素解码前清零后复用 fn 返回后不要再持有 obj
+func (b jsonBinding) BindStream(r *http.Request, obj any, fn func(elem any) error) error {
+	if r.Body == nil {
+		return errors.New("invalid request")
 	}
-	for i := 0; i < elem.NumField(); i++ {
-		field := elem.Field(i)
-		jsonName := field.Tag.Get("json")
-		required := field.Tag.Get("spxgo")
-		for _, v := range mapData {
-			if v[jsonName] == nil && required == "required" {
-				return errors.New(
-					fmt.Sprintf("field [%s] is required", jsonName))
-			}
-		}
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return errors.New("This argument must have a pointer type ")
 	}
-	b, err := json.Marshal(mapData) // 将数据编码成json字符串
+	// 逐个读取元素需要 Token 见 codec.JSONTokenDecoder
+	decoder := tokenDecoder(r.Body)
+	token, err := decoder.Token()
 	if err != nil {
 		return err
 	}
-	if err = json.Unmarshal(b, obj); err != nil { // 将json字符串解码到相应的数据结构
-		return err
+	if token != json.Delim('[') {
+		return errors.New("json stream must be an array")
+	}
+	zero := reflect.Zero(value.Elem().Type())
+	for i := 0; decoder.More(); i++ {
+		value.Elem().Set(zero)
+		if err = b.decodeElement(r, decoder, obj); err != nil {
+			return &ElementError{Index: i, Err: err}
+		}
+		if err = fn(obj); err != nil {
+			return err
+		}
 	}
+	_, err = decoder.Token()
 	return err
 }
@@ -0,0 +1,222 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"gitbuh.com/spxzx/spxgo/codec"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jsonAddress struct {
+	City   string `json:"city" spxgo:"required"`
+	Street string `json:"street,omitempty" spxgo:"required"`
+}
+
+type jsonBase struct {
+	ID int64 `json:"id,string" spxgo:"required"`
+}
+
+type JSONMeta struct {
+	Source string `json:"source" spxgo:"required"`
+}
+
+type jsonItem struct {
+	Name string `json:"name" spxgo:"required"`
+}
+
+type jsonUser struct {
+	jsonBase
+	*JSONMeta                        // 未导出类型的嵌入指针无法创建 encoding/json 也不支持
+	Name      string                 `spxgo:"required"` // 没有json标签时使用属性名
+	Age       *int                   `json:"age" spxgo:"required"`
+	Address   jsonAddress            `json:"address" spxgo:"required"`
+	Backup    *jsonAddress           `json:"backup"`
+	Items     []jsonItem             `json:"items"`
+	Pairs     map[string]jsonItem    `json:"pairs"`
+	Big       uint64                 `json:"big"`
+	Extra     map[string]any         `json:"extra"`
+	Birthday  time.Time              `json:"birthday"`
+	Raw       json.RawMessage        `json:"raw"`
+	Nested    [2]map[string]jsonItem `json:"nested"`
+}
+
+func bindJSON(b jsonBinding, body string, obj any) error {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	return b.Bind(r, obj)
+}
+
+func TestJSONRequiredAllPresent(t *testing.T) {
+	body := `{
+		"id": "9007199254740993",
+		"source": "web",
+		"Name": "bob",
+		"age": 0,
+		"address": {"city": "Shanghai", "street": "Nanjing Rd"},
+		"items": [{"name": "a"}, {"name": "b"}],
+		"pairs": {"x": {"name": "c"}},
+		"big": 18446744073709551615,
+		"extra": {"n": 9007199254740993},
+		"birthday": "2000-01-02T00:00:00Z",
+		"raw": {"keep": [1, 2]},
+		"nested": [{"k": {"name": "d"}}]
+	}`
+	var u jsonUser
+	if err := bindJSON(jsonBinding{IsValidate: true, UseNumber: true}, body, &u); err != nil {
+		t.Fatal(err)
+	}
+	// 大整数不经过 float64 不会丢失精度
+	if u.ID != 9007199254740993 || u.Big != 18446744073709551615 {
+		t.Fatalf("big int: id=%d big=%d", u.ID, u.Big)
+	}
+	if n, ok := u.Extra["n"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Fatalf("UseNumber: %#v", u.Extra["n"])
+	}
+	if u.JSONMeta == nil || u.Source != "web" || u.Name != "bob" || u.Age == nil || *u.Age != 0 {
+		t.Fatalf("fields: %+v", u)
+	}
+	if u.Address.City != "Shanghai" || u.Backup != nil {
+		t.Fatalf("nested: %+v %+v", u.Address, u.Backup)
+	}
+	if !reflect.DeepEqual(u.Items, []jsonItem{{"a"}, {"b"}}) || u.Pairs["x"].Name != "c" || u.Nested[0]["k"].Name != "d" {
+		t.Fatalf("collections: %+v %+v %+v", u.Items, u.Pairs, u.Nested)
+	}
+	if !u.Birthday.Equal(time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)) || string(u.Raw) != `{"keep": [1, 2]}` {
+		t.Fatalf("opaque: %v %s", u.Birthday, u.Raw)
+	}
+}
+
+func TestJSONRequiredMissing(t *testing.T) {
+	// null 和没有传一样 omitempty 等标签选项不影响属性名
+	body := `{
+		"age": null,
+		"address": {"street": ""},
+		"backup": {"city": "Beijing"},
+		"items": [{"name": "a"}, {}],
+		"pairs": {"x": {}},
+		"nested": [{}, {"k": {}}]
+	}`
+	var u jsonUser
+	err := bindJSON(jsonBinding{IsValidate: true}, body, &u)
+	var missing MissingFieldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("err = %v, want MissingFieldsError", err)
+	}
+	want := []string{
+		"address.city",
+		"backup.street",
+		"items[1].name",
+		"pairs.x.name",
+		"nested[1].k.name",
+		"Name",
+		"age",
+		"id",
+		"source", // 嵌入结构体的属性提升到当前层级
+	}
+	got := map[string]bool{}
+	for _, path := range missing {
+		got[path] = true
+	}
+	for _, path := range want {
+		if !got[path] {
+			t.Errorf("missing %q not reported, got %v", path, missing)
+		}
+	}
+	// address 本身传了 street 为空字符串不算缺少
+	for _, path := range []string{"address.street", "address"} {
+		if got[path] {
+			t.Errorf("%q should not be reported", path)
+		}
+	}
+}
+
+func TestJSONRequiredErrors(t *testing.T) {
+	var u jsonUser
+	err := bindJSON(jsonBinding{IsValidate: true}, `{"address": "Shanghai"}`, &u)
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "address" || typeErr.Value != "string" {
+		t.Fatalf("err = %v, want type error for address", err)
+	}
+	err = bindJSON(jsonBinding{IsValidate: true, DisallowUnknownFields: true}, `{"unknown": 1}`, &u)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "unknown"`) {
+		t.Fatalf("err = %v, want unknown field", err)
+	}
+	if err = bindJSON(jsonBinding{IsValidate: true}, `{"address": {`, &u); err == nil {
+		t.Fatal("expected error for truncated json")
+	}
+}
+
+func TestJSONBindStreamRequired(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"name": "a"}, {"name": "b"}, {}]`))
+	var names []string
+	err := jsonBinding{IsValidate: true}.BindStream(r, &jsonItem{}, func(elem any) error {
+		names = append(names, elem.(*jsonItem).Name)
+		return nil
+	})
+	var elemErr *ElementError
+	if !errors.As(err, &elemErr) || elemErr.Index != 2 {
+		t.Fatalf("err = %v, want error at element 2", err)
+	}
+	var missing MissingFieldsError
+	if !errors.As(err, &missing) || missing[0] != "name" {
+		t.Fatalf("err = %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatalf("names = %v", names)
+	}
+}
+
+// tokenCodec 记录 Token 的调用次数 plain 为 true 时解码器不能逐个读取 token
+type tokenCodec struct {
+	codec.StdJSON
+	tokens *int
+	plain  bool
+}
+
+type countingDecoder struct {
+	*json.Decoder
+	tokens *int
+}
+
+func (d countingDecoder) Token() (json.Token, error) {
+	*d.tokens++
+	return d.Decoder.Token()
+}
+
+func (c tokenCodec) NewDecoder(r io.Reader) codec.JSONDecoder {
+	if c.plain {
+		return struct{ codec.JSONDecoder }{json.NewDecoder(r)}
+	}
+	return countingDecoder{Decoder: json.NewDecoder(r), tokens: c.tokens}
+}
+
+func TestJSONBindUsesCodec(t *testing.T) {
+	defer func(old codec.JSONCodec) { codec.JSON = old }(codec.JSON)
+	for _, plain := range []bool{false, true} {
+		tokens := 0
+		codec.JSON = tokenCodec{tokens: &tokens, plain: plain}
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "a"}`))
+		var item jsonItem
+		if err := (jsonBinding{IsValidate: true}).Bind(r, &item); err != nil || item.Name != "a" {
+			t.Fatalf("plain=%v: err = %v item = %+v", plain, err, item)
+		}
+		r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"name": "a"}, {"name": "b"}]`))
+		count := 0
+		err := jsonBinding{IsValidate: true}.BindStream(r, &jsonItem{}, func(elem any) error {
+			count++
+			return nil
+		})
+		if err != nil || count != 2 {
+			t.Fatalf("plain=%v: err = %v count = %d", plain, err, count)
+		}
+		// 解码器可以读取 token 时文档结构也通过 codec.JSON 读取 否则退回 encoding/json
+		if plain != (tokens == 0) {
+			t.Fatalf("plain=%v: Token called %d times", plain, tokens)
+		}
+	}
+}
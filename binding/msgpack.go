@@ -0,0 +1,25 @@
+package binding
+
+import (
+	"errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+type msgpackBinding struct {
+}
+
+func (msgpackBinding) Name() string {
+	return "msgpack"
+}
+
+// Bind 属性使用 `msgpack:"..."` 标记 没有标记时使用属性名
+func (b msgpackBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("invalid request")
+	}
+	if err := msgpack.NewDecoder(r.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
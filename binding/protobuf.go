@@ -0,0 +1,34 @@
+package binding
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+type protobufBinding struct {
+}
+
+func (protobufBinding) Name() string {
+	return "protobuf"
+}
+
+// Bind obj 必须是 proto.Message 比如 protoc 生成的 *pb.User
+func (b protobufBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("invalid request")
+	}
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return errors.New("obj is not proto.Message")
+	}
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err = proto.Unmarshal(buf, msg); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
@@ -0,0 +1,395 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gitbuh.com/spxzx/spxgo/codec"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// MissingFieldsError 没有传入的 `spxgo:"required"` 属性 值为json路径 比如 address.city items[0].name
+type MissingFieldsError []string
+
+func (e MissingFieldsError) Error() string {
+	return "required fields missing: " + strings.Join(e, ", ")
+}
+
+// jsonField 结构体中参与json解码的一个属性
+type jsonField struct {
+	name     string
+	required bool
+	typ      reflect.Type
+	index    []int // 嵌入结构体中的属性 index 有多层
+	quoted   bool  // `json:",string"` 值被编码在字符串中
+}
+
+var jsonFieldsCache sync.Map // reflect.Type -> []jsonField
+
+// jsonFields 与 encoding/json 的规则一致: 有json标签用标签名 "-" 忽略 没有标签用属性名
+// 没有标签的嵌入结构体中的属性提升到当前层级
+func jsonFields(t reflect.Type) []jsonField {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.([]jsonField)
+	}
+	fields := make([]jsonField, 0, t.NumField())
+	var embedded []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, f := range jsonFields(ft) {
+					f.index = append([]int{i}, f.index...)
+					embedded = append(embedded, f)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{
+			name:     name,
+			required: hasOption(field.Tag.Get("spxgo"), "required"),
+			typ:      field.Type,
+			index:    []int{i},
+			quoted:   hasOption(opts, "string") && quotable(field.Type),
+		})
+	}
+	// 外层的同名属性优先
+	for _, f := range embedded {
+		if indexField(fields, f.name) < 0 {
+			fields = append(fields, f)
+		}
+	}
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+func hasOption(tag, option string) bool {
+	for _, o := range strings.Split(tag, ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// quotable 与 encoding/json 一致 ",string" 只对基础类型生效
+func quotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func indexField(fields []jsonField, name string) int {
+	for i, f := range fields {
+		if f.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchField 先精确匹配 再像 encoding/json 一样忽略大小写匹配
+func matchField(fields []jsonField, key string) int {
+	if i := indexField(fields, key); i >= 0 {
+		return i
+	}
+	for i, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// fieldByIndex 取出嵌入结构体中的属性 途中为空的嵌入指针会被创建
+// 未导出的嵌入指针无法创建 返回 false
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// opaque 自己实现了解码的类型不再往下检查
+func opaque(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// walkable 类型中可能含有必填属性 需要按结构逐层解码 其它类型整个值直接解码
+func walkable(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if opaque(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Slice, reflect.Array:
+		return walkable(t.Elem())
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && walkable(t.Elem())
+	}
+	return false
+}
+
+// requiredDecoder 只读一遍json 一边解码到目标一边记录哪些属性出现过
+// 对象和数组按照目标的类型逐层读取 其余的值(数字 字符串 time.Time 等)取出原始内容后直接解码到属性
+// 不经过 map[string]any 中转 大整数不会丢失精度
+type requiredDecoder struct {
+	decoder codec.JSONTokenDecoder
+	binding jsonBinding
+	missing MissingFieldsError
+}
+
+// decodeRequired 从 decoder 读取下一个值解码到 obj 缺少 `spxgo:"required"` 的属性时返回 MissingFieldsError
+// 所有缺少的属性都会被收集 路径如 address.city items[0].name
+func (b jsonBinding) decodeRequired(decoder codec.JSONTokenDecoder, obj any) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("This argument must have a pointer type ")
+	}
+	d := &requiredDecoder{decoder: decoder, binding: b}
+	if _, err := d.decodeValue(v.Elem(), ""); err != nil {
+		return err
+	}
+	if len(d.missing) > 0 {
+		return d.missing
+	}
+	return nil
+}
+
+// decodeValue 读取下一个值解码到 v 返回该值是否为 null
+func (d *requiredDecoder) decodeValue(v reflect.Value, path string) (bool, error) {
+	if !walkable(v.Type()) {
+		return d.decodeLeaf(v)
+	}
+	token, err := d.decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	if token == nil {
+		// 与 encoding/json 一致 null 把指针 切片 map 置空 其它类型保持不变
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return true, nil
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	delim, _ := token.(json.Delim)
+	switch {
+	case delim == '{' && v.Kind() == reflect.Struct:
+		return false, d.decodeObject(v, path)
+	case delim == '{' && v.Kind() == reflect.Map:
+		return false, d.decodeMap(v, path)
+	case delim == '[' && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		return false, d.decodeArray(v, path)
+	}
+	return false, &json.UnmarshalTypeError{Value: tokenKind(token), Type: v.Type(), Offset: d.decoder.InputOffset(), Field: path}
+}
+
+// decodeLeaf 不含必填属性的值 使用 codec.JSON 直接解码
+func (d *requiredDecoder) decodeLeaf(v reflect.Value) (bool, error) {
+	var raw json.RawMessage
+	if err := d.decoder.Decode(&raw); err != nil {
+		return false, err
+	}
+	if err := d.binding.newDecoder(bytes.NewReader(raw)).Decode(v.Addr().Interface()); err != nil {
+		return false, err
+	}
+	return bytes.Equal(raw, []byte("null")), nil
+}
+
+// decodeQuoted `json:",string"` 的属性 先取出字符串 再把字符串的内容解码到属性
+func (d *requiredDecoder) decodeQuoted(v reflect.Value) (bool, error) {
+	var raw json.RawMessage
+	if err := d.decoder.Decode(&raw); err != nil {
+		return false, err
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return true, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %s into %v", raw, v.Type())
+	}
+	if err := d.binding.newDecoder(strings.NewReader(s)).Decode(v.Addr().Interface()); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (d *requiredDecoder) skip() error {
+	var raw json.RawMessage
+	return d.decoder.Decode(&raw)
+}
+
+func (d *requiredDecoder) decodeObject(v reflect.Value, path string) error {
+	fields := jsonFields(v.Type())
+	present := make([]bool, len(fields))
+	for d.decoder.More() {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		i := matchField(fields, key)
+		if i < 0 {
+			if d.binding.DisallowUnknownFields {
+				return fmt.Errorf("json: unknown field %q", key)
+			}
+			if err = d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		field, ok := fieldByIndex(v, fields[i].index)
+		if !ok {
+			if err = d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		var isNull bool
+		if fields[i].quoted {
+			isNull, err = d.decodeQuoted(field)
+		} else {
+			isNull, err = d.decodeValue(field, joinPath(path, fields[i].name))
+		}
+		if err != nil {
+			return err
+		}
+		// null 和没有传一样 都当作缺少
+		present[i] = !isNull
+	}
+	for i, f := range fields {
+		if f.required && !present[i] {
+			d.missing = append(d.missing, joinPath(path, f.name))
+		}
+	}
+	// 读取结尾的 }
+	_, err := d.decoder.Token()
+	return err
+}
+
+func (d *requiredDecoder) decodeMap(v reflect.Value, path string) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	for d.decoder.More() {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		elem := reflect.New(t.Elem()).Elem()
+		if _, err = d.decodeValue(elem, joinPath(path, key)); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	_, err := d.decoder.Token()
+	return err
+}
+
+func (d *requiredDecoder) decodeArray(v reflect.Value, path string) error {
+	i := 0
+	if v.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(v.Type(), 0, 0)
+		for ; d.decoder.More(); i++ {
+			slice = reflect.Append(slice, reflect.Zero(v.Type().Elem()))
+			if _, err := d.decodeValue(slice.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	} else {
+		for ; d.decoder.More(); i++ {
+			var err error
+			// 超出数组长度的元素丢弃
+			if i < v.Len() {
+				_, err = d.decodeValue(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+			} else {
+				err = d.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	}
+	_, err := d.decoder.Token()
+	return err
+}
+
+// tokenKind 类型不匹配时错误中的值类型 与 encoding/json 的描述一致
+func tokenKind(token json.Token) string {
+	switch token {
+	case json.Delim('{'):
+		return "object"
+	case json.Delim('['):
+		return "array"
+	}
+	switch token.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	}
+	return "number"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,96 @@
+package binding
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
+// 以下注册函数需要在启动时 处理请求之前调用
+
+var ErrValidatorNotSupported = errors.New("binding: Validator.Engine() is not *validator.Validate")
+
+func validatorEngine() (*validator.Validate, error) {
+	v, ok := Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil, ErrValidatorNotSupported
+	}
+	return v, nil
+}
+
+// RegisterValidation 添加校验规则 `validate:"tag"`
+func RegisterValidation(tag string, fn validator.Func, callValidationEvenIfNull ...bool) error {
+	v, err := validatorEngine()
+	if err != nil {
+		return err
+	}
+	return v.RegisterValidation(tag, fn, callValidationEvenIfNull...)
+}
+
+// RegisterValidationCtx 添加需要请求上下文的校验规则 ctx 为绑定时 http.Request 的 Context
+// 在 spxgo 中可以用 spxgo.FromContext(ctx) 取到当前请求的 *spxgo.Context 比如检查数据库中是否重名
+func RegisterValidationCtx(tag string, fn validator.FuncCtx, callValidationEvenIfNull ...bool) error {
+	v, err := validatorEngine()
+	if err != nil {
+		return err
+	}
+	return v.RegisterValidationCtx(tag, fn, callValidationEvenIfNull...)
+}
+
+// RegisterCrossFieldValidation 添加比较两个属性的规则 `validate:"tag=Other"`
+// 参数为同一结构体中另一个属性的名字 找不到该属性时校验失败
+func RegisterCrossFieldValidation(tag string, fn func(field, other reflect.Value) bool) error {
+	return RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+		other, _, _, found := fl.GetStructFieldOK2()
+		if !found {
+			return false
+		}
+		return fn(fl.Field(), other)
+	})
+}
+
+// RegisterStructValidation 添加结构体级别的校验 types 为需要校验的结构体的值
+// fn 中用 sl.ReportError 报告的错误同样会出现在 ValidationError 中
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...any) error {
+	v, err := validatorEngine()
+	if err != nil {
+		return err
+	}
+	v.RegisterStructValidation(fn, types...)
+	return nil
+}
+
+// RegisterStructValidationCtx 同 RegisterStructValidation fn 可以拿到请求上下文
+func RegisterStructValidationCtx(fn validator.StructLevelFuncCtx, types ...any) error {
+	v, err := validatorEngine()
+	if err != nil {
+		return err
+	}
+	v.RegisterStructValidationCtx(fn, types...)
+	return nil
+}
+
+// RegisterTranslation 为自定义规则添加提示 text 中 {0} 为属性名 {1} 为规则参数
+// locale 为 Translator 支持的语言 比如 "en" "zh"
+func RegisterTranslation(locale, tag, text string) error {
+	v, err := validatorEngine()
+	if err != nil {
+		return err
+	}
+	initTranslator()
+	trans, found := uni.FindTranslator(locale)
+	if !found {
+		return errors.New("binding: unknown locale " + locale)
+	}
+	return v.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+		return trans.Add(tag, text, true)
+	}, func(trans ut.Translator, fe validator.FieldError) string {
+		msg, err := trans.T(tag, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return msg
+	})
+}
@@ -0,0 +1,144 @@
+package binding
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ctxKey struct{}
+
+// validateFields 校验 obj 返回 Rule 和 Message
+func validateFields(t *testing.T, ctx context.Context, obj any, locale string) []FieldError {
+	t.Helper()
+	err := validate(ctx, obj)
+	if err == nil {
+		return nil
+	}
+	ve, ok := NewValidationError(err, Translator(locale))
+	if !ok {
+		t.Fatalf("not a validation error: %v", err)
+	}
+	return ve.Errors
+}
+
+func TestRegisterValidation(t *testing.T) {
+	if err := RegisterValidation("rules_even", func(fl validator.FieldLevel) bool {
+		return fl.Field().Int()%2 == 0
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTranslation("en", "rules_even", "{0} must be even"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTranslation("zh", "rules_even", "{0}必须是偶数"); err != nil {
+		t.Fatal(err)
+	}
+	type req struct {
+		N int `json:"n" validate:"rules_even"`
+	}
+	if errs := validateFields(t, context.Background(), &req{N: 2}, "en"); errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+	want := []FieldError{{Field: "n", Rule: "rules_even", Message: "n must be even"}}
+	if errs := validateFields(t, context.Background(), &req{N: 3}, "en"); !reflect.DeepEqual(errs, want) {
+		t.Fatalf("errors = %+v, want %+v", errs, want)
+	}
+	if errs := validateFields(t, context.Background(), &req{N: 3}, "zh"); len(errs) != 1 || errs[0].Message != "n必须是偶数" {
+		t.Fatalf("errors = %+v", errs)
+	}
+	if err := RegisterTranslation("xx", "rules_even", "{0}"); err == nil {
+		t.Fatal("expected error for unknown locale")
+	}
+}
+
+func TestRegisterValidationCtx(t *testing.T) {
+	// 从请求上下文中取出已经存在的名字
+	if err := RegisterValidationCtx("rules_unique", func(ctx context.Context, fl validator.FieldLevel) bool {
+		taken, _ := ctx.Value(ctxKey{}).(string)
+		return fl.Field().String() != taken
+	}); err != nil {
+		t.Fatal(err)
+	}
+	type req struct {
+		Name string `json:"name" validate:"rules_unique"`
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "bob")
+	if errs := validateFields(t, ctx, &req{Name: "alice"}, "en"); errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+	if errs := validateFields(t, ctx, &req{Name: "bob"}, "en"); len(errs) != 1 || errs[0].Rule != "rules_unique" {
+		t.Fatalf("errors = %+v", errs)
+	}
+}
+
+func TestRegisterCrossFieldValidation(t *testing.T) {
+	if err := RegisterCrossFieldValidation("rules_after", func(field, other reflect.Value) bool {
+		return field.Int() > other.Int()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	type req struct {
+		Start int `json:"start"`
+		End   int `json:"end" validate:"rules_after=Start"`
+	}
+	if errs := validateFields(t, context.Background(), &req{Start: 1, End: 2}, "en"); errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+	errs := validateFields(t, context.Background(), &req{Start: 2, End: 1}, "en")
+	if len(errs) != 1 || errs[0].Field != "end" || errs[0].Rule != "rules_after" || errs[0].Param != "Start" {
+		t.Fatalf("errors = %+v", errs)
+	}
+	// 找不到参数中的属性时校验失败
+	type missing struct {
+		End int `json:"end" validate:"rules_after=Nope"`
+	}
+	if errs := validateFields(t, context.Background(), &missing{End: 1}, "en"); len(errs) != 1 {
+		t.Fatalf("errors = %+v", errs)
+	}
+}
+
+type rulesPassword struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+type rulesOrder struct {
+	Coupon string `json:"coupon"`
+}
+
+func TestRegisterStructValidation(t *testing.T) {
+	if err := RegisterStructValidation(func(sl validator.StructLevel) {
+		p := sl.Current().Interface().(rulesPassword)
+		if p.Password != p.Confirm {
+			sl.ReportError(p.Confirm, "confirm", "Confirm", "eqfield", "password")
+		}
+	}, rulesPassword{}); err != nil {
+		t.Fatal(err)
+	}
+	if errs := validateFields(t, context.Background(), &rulesPassword{"a", "a"}, "en"); errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+	errs := validateFields(t, context.Background(), &rulesPassword{"a", "b"}, "en")
+	if len(errs) != 1 || errs[0].Field != "confirm" || errs[0].Rule != "eqfield" || !strings.Contains(errs[0].Message, "password") {
+		t.Fatalf("errors = %+v", errs)
+	}
+
+	if err := RegisterStructValidationCtx(func(ctx context.Context, sl validator.StructLevel) {
+		valid, _ := ctx.Value(ctxKey{}).(string)
+		if o := sl.Current().Interface().(rulesOrder); o.Coupon != "" && o.Coupon != valid {
+			sl.ReportError(o.Coupon, "coupon", "Coupon", "coupon", "")
+		}
+	}, rulesOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "SAVE10")
+	if errs := validateFields(t, ctx, &rulesOrder{Coupon: "SAVE10"}, "en"); errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+	if errs := validateFields(t, ctx, &rulesOrder{Coupon: "FREE"}, "en"); len(errs) != 1 || errs[0].Rule != "coupon" {
+		t.Fatalf("errors = %+v", errs)
+	}
+}
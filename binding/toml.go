@@ -0,0 +1,25 @@
+package binding
+
+import (
+	"errors"
+	"github.com/BurntSushi/toml"
+	"net/http"
+)
+
+type tomlBinding struct {
+}
+
+func (tomlBinding) Name() string {
+	return "toml"
+}
+
+// Bind 属性使用 `toml:"..."` 标记 没有标记时忽略大小写匹配属性名
+func (b tomlBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("invalid request")
+	}
+	if _, err := toml.NewDecoder(r.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
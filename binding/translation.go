@@ -0,0 +1,55 @@
+package binding
+
+import (
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
+	"sync"
+)
+
+var (
+	transOnce sync.Once
+	transMu   sync.Mutex
+	uni       *ut.UniversalTranslator
+)
+
+// initTranslator 默认支持英文和中文 英文为兜底语言
+func initTranslator() {
+	transOnce.Do(func() {
+		uni = ut.New(en.New())
+		_ = registerTranslator(en.New(), enTranslations.RegisterDefaultTranslations)
+		_ = registerTranslator(zh.New(), zhTranslations.RegisterDefaultTranslations)
+	})
+}
+
+// RegisterTranslator 添加一种语言 需要在启动时调用 register 为 validator/v10/translations 下对应语言的注册函数
+// 使用自定义的 Validator 且 Engine() 不是 *validator.Validate 时只添加语言不注册提示
+func RegisterTranslator(locale locales.Translator, register func(*validator.Validate, ut.Translator) error) error {
+	initTranslator()
+	return registerTranslator(locale, register)
+}
+
+func registerTranslator(locale locales.Translator, register func(*validator.Validate, ut.Translator) error) error {
+	transMu.Lock()
+	defer transMu.Unlock()
+	if err := uni.AddTranslator(locale, true); err != nil {
+		return err
+	}
+	trans, _ := uni.GetTranslator(locale.Locale())
+	v, ok := Validator.Engine().(*validator.Validate)
+	if !ok || register == nil {
+		return nil
+	}
+	return register(v, trans)
+}
+
+// Translator 按顺序返回第一个支持的语言 比如 "zh_CN" "zh" 都不支持时返回英文
+func Translator(locales ...string) ut.Translator {
+	initTranslator()
+	trans, _ := uni.FindTranslator(locales...)
+	return trans
+}
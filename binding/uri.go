@@ -0,0 +1,43 @@
+package binding
+
+import (
+	"context"
+	"net/http"
+)
+
+type paramsKey struct{}
+
+// WithParams 把路由匹配到的路径参数放到请求中 供 Uri 绑定器使用
+func WithParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+}
+
+// Params 取出 WithParams 放入的路径参数
+func Params(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params
+}
+
+type uriBinding struct {
+}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+// Bind 路径参数 /user/:id 绑定到 `uri:"id"` 标记的属性上
+func (b uriBinding) Bind(r *http.Request, obj any) error {
+	if err := b.bind(r, obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
+
+func (uriBinding) bind(r *http.Request, obj any) error {
+	params := Params(r)
+	values := make(map[string][]string, len(params))
+	for k, v := range params {
+		values[k] = []string{v}
+	}
+	return mapFormByTag(obj, values, nil, "uri")
+}
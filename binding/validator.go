@@ -1,6 +1,7 @@
 package binding
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"reflect"
@@ -17,28 +18,25 @@ type StructValidator interface {
 	Engine() any
 }
 
+// ContextValidator 可选接口 校验时带上请求的 Context 供 RegisterValidationCtx 注册的规则使用
+type ContextValidator interface {
+	ValidateStructCtx(context.Context, any) error
+}
+
 type SliceValidationError []error
 
 func (err SliceValidationError) Error() string {
-	n := len(err)
-	switch n {
-	case 0:
-		return ""
-	default:
-		var b strings.Builder
-		if err[0] != nil {
-			_, _ = fmt.Fprintf(&b, "[%d]: %s", 0, err[0].Error())
+	var b strings.Builder
+	for i, e := range err {
+		if e == nil {
+			continue // 没有错误的元素
 		}
-		if n > 1 {
-			for i := 1; i < n; i++ {
-				if err[i] != nil {
-					b.WriteString("\n")
-					_, _ = fmt.Fprintf(&b, "[%d]: %s", i, err[i].Error())
-				}
-			}
+		if b.Len() > 0 {
+			b.WriteString("\n")
 		}
-		return b.String()
+		_, _ = fmt.Fprintf(&b, "[%d]: %s", i, e.Error())
 	}
+	return b.String()
 }
 
 type defaultValidator struct {
@@ -50,30 +48,45 @@ type defaultValidator struct {
 func (d *defaultValidator) lazyInit() {
 	d.one.Do(func() {
 		d.validate = validator.New()
+		// 错误中使用json中的名字 方便直接返回给客户端
+		d.validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
 	})
 }
 
-func (d *defaultValidator) validateStruct(obj any) error {
+func (d *defaultValidator) validateStruct(ctx context.Context, obj any) error {
 	d.lazyInit()
-	return d.validate.Struct(obj)
+	return d.validate.StructCtx(ctx, obj)
 }
 
 func (d *defaultValidator) ValidateStruct(obj any) error {
+	return d.ValidateStructCtx(context.Background(), obj)
+}
+
+func (d *defaultValidator) ValidateStructCtx(ctx context.Context, obj any) error {
 	valueOf := reflect.ValueOf(obj)
 	switch valueOf.Kind() {
 	case reflect.Pointer:
-		return d.ValidateStruct(valueOf.Elem().Interface())
+		return d.ValidateStructCtx(ctx, valueOf.Elem().Interface())
 	case reflect.Struct:
-		return d.validateStruct(obj)
+		return d.validateStruct(ctx, obj)
 	case reflect.Slice, reflect.Array:
 		count := valueOf.Len()
-		sliceValidationError := make(SliceValidationError, 0)
+		// 保留没有错误的位置 下标和传入的数组一致
+		sliceValidationError := make(SliceValidationError, count)
+		failed := false
 		for i := 0; i < count; i++ {
-			if err := d.validateStruct(valueOf.Index(i).Interface()); err != nil {
-				sliceValidationError = append(sliceValidationError, err)
+			if err := d.validateStruct(ctx, valueOf.Index(i).Interface()); err != nil {
+				sliceValidationError[i] = err
+				failed = true
 			}
 		}
-		if len(sliceValidationError) == 0 {
+		if !failed {
 			return nil
 		}
 		return sliceValidationError // 因为重写了Error() 所以返回这个不报错
@@ -87,6 +100,9 @@ func (d *defaultValidator) Engine() any {
 	return d.validate
 }
 
-func validate(obj any) error {
+func validate(ctx context.Context, obj any) error {
+	if v, ok := Validator.(ContextValidator); ok {
+		return v.ValidateStructCtx(ctx, obj)
+	}
 	return Validator.ValidateStruct(obj)
 }
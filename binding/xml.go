@@ -1,6 +1,7 @@
 package binding
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"io"
@@ -18,13 +19,13 @@ func (b xmlBinding) Bind(r *http.Request, obj any) error {
 	if r.Body == nil {
 		return errors.New("invalid request")
 	}
-	return decodeXML(r.Body, obj)
+	return decodeXML(r.Context(), r.Body, obj)
 }
 
-func decodeXML(r io.Reader, obj any) error {
+func decodeXML(ctx context.Context, r io.Reader, obj any) error {
 	decoder := xml.NewDecoder(r)
 	if err := decoder.Decode(obj); err != nil {
 		return err
 	}
-	return validate(obj)
+	return validate(ctx, obj)
 }
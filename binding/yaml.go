@@ -0,0 +1,25 @@
+package binding
+
+import (
+	"errors"
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type yamlBinding struct {
+}
+
+func (yamlBinding) Name() string {
+	return "yaml"
+}
+
+// Bind 属性使用 `yaml:"..."` 标记 没有标记时使用小写的属性名
+func (b yamlBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("invalid request")
+	}
+	if err := yaml.NewDecoder(r.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(r.Context(), obj)
+}
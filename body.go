@@ -0,0 +1,123 @@
+package spxgo
+
+import (
+	"bytes"
+	"errors"
+	"gitbuh.com/spxzx/spxgo/binding"
+	"gitbuh.com/spxzx/spxgo/serror"
+	"io"
+	"net/http"
+)
+
+// MaxBodySize 路由级别的请求体大小限制 覆盖 Engine.MaxBodySize
+// Content-Length 已经超出时直接返回413 否则读取超出时绑定返回413
+func MaxBodySize(n int64) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if n > 0 && c.R.ContentLength > n {
+				_ = c.Problem(serror.NewProblem(http.StatusRequestEntityTooLarge, "request body too large"))
+				return
+			}
+			c.SetMaxBodySize(n)
+			next(c)
+		}
+	}
+}
+
+// SetMaxBodySize 限制请求体最多读取 n 个字节 n<=0 不限制 需要在读取请求体之前调用
+func (c *Context) SetMaxBodySize(n int64) {
+	if c.body == nil {
+		return
+	}
+	if n <= 0 {
+		c.R.Body = c.body
+		return
+	}
+	c.R.Body = http.MaxBytesReader(c.W, c.body, n)
+}
+
+// isBodyTooLarge 读取请求体时超出了 MaxBodySize
+func isBodyTooLarge(err error) bool {
+	var maxBytesError *http.MaxBytesError
+	return errors.As(err, &maxBytesError)
+}
+
+// BindError Bind 系列方法失败时返回并记录到 Context.Error 的错误 Status 为对应的状态码
+// 请求体过大413 不支持的 Content-Type 415 校验失败422 其它400
+type BindError struct {
+	Status int
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// bindFail 不直接写入响应 记录错误交给 ErrorHandler 处理函数可以在返回前自己生成响应
+func (c *Context) bindFail(err error) error {
+	status := http.StatusBadRequest
+	switch {
+	case isBodyTooLarge(err):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, binding.ErrUnsupportedMediaType):
+		status = http.StatusUnsupportedMediaType
+	default:
+		if _, ok := binding.NewValidationError(err, nil); ok {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	return c.Error(&BindError{Status: status, Err: err})
+}
+
+// BindJSONStream 请求体为json数组时逐个绑定元素 用于批量导入 不会把整个数组读入内存
+// obj 为元素的指针 每个元素解码并校验后调用 fn fn 返回后 obj 会被下一个元素覆盖
+// 元素的错误为 *binding.ElementError 可以直接交给 FailValidation
+func (c *Context) BindJSONStream(obj any, fn func(elem any) error) error {
+	return c.jsonBinding().(binding.StreamBinding).BindStream(c.R, obj, fn)
+}
+
+// BodyBytes 读出整个请求体并缓存在 Context 中 同样受 MaxBodySize 限制
+// 读取后 c.R.Body 已经读完 需要再次绑定时使用 ShouldBindBodyWith
+func (c *Context) BodyBytes() ([]byte, error) {
+	if c.bodyBytes != nil {
+		return c.bodyBytes, nil
+	}
+	if c.R.Body == nil {
+		return nil, errors.New("invalid request")
+	}
+	body, err := io.ReadAll(c.R.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.bodyBytes = body
+	return body, nil
+}
+
+// ShouldBindBodyWith 使用缓存的请求体绑定 可以多次调用 比如中间件先审计json 处理函数再绑定
+func (c *Context) ShouldBindBodyWith(obj any, bind binding.Binding) error {
+	body, err := c.BodyBytes()
+	if err != nil {
+		return err
+	}
+	// 只有默认的 binding.JSON 使用 Context 中的设置 调用方自己配置的绑定器原样使用
+	if bind == binding.Binding(binding.JSON) {
+		bind = c.jsonBinding()
+	}
+	// 浅拷贝请求 每次都给一个新的 Body 不影响 c.R
+	r := *c.R
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.Form, r.PostForm, r.MultipartForm = nil, nil, nil
+	return bind.Bind(&r, obj)
+}
+
+// BindBodyWith 同 ShouldBindBodyWith 失败时同 MustBindWith
+func (c *Context) BindBodyWith(obj any, bind binding.Binding) error {
+	if err := c.ShouldBindBodyWith(obj, bind); err != nil {
+		return c.bindFail(err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package spxgo
+
+import (
+	"errors"
+	"gitbuh.com/spxzx/spxgo/binding"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestEngine 不带日志等中间件的 Engine
+func newTestEngine() *Engine {
+	return New()
+}
+
+// serve 向 e 发起一次请求 header 中的值设置为请求头
+func serve(e *Engine, method, target string, body io.Reader, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, body)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	return w
+}
+
+// postJSON chunked 为 true 时不带 Content-Length 只能在读取时发现超出限制
+func postJSON(e *Engine, path, body string, chunked bool) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if chunked {
+		r.ContentLength = -1
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	return w
+}
+
+func TestMaxBodySize(t *testing.T) {
+	e := newTestEngine()
+	e.MaxBodySize = 16
+	bind := func(c *Context) {
+		var m map[string]any
+		if err := c.ShouldBindWith(&m, binding.JSON); err != nil {
+			_ = c.FailValidation(err)
+			return
+		}
+		_ = c.String(http.StatusOK, "ok")
+	}
+	g := e.Group("api")
+	g.Post("/default", bind)
+	// 路由级别的限制覆盖 Engine 的默认值
+	g.Post("/upload", bind, MaxBodySize(1024))
+	small := `{"a": 1}`
+	large := `{"a": "` + strings.Repeat("x", 100) + `"}`
+	cases := []struct {
+		path, body string
+		chunked    bool
+		status     int
+	}{
+		{"/api/default", small, false, http.StatusOK},
+		{"/api/default", large, false, http.StatusRequestEntityTooLarge},
+		{"/api/default", large, true, http.StatusRequestEntityTooLarge},
+		{"/api/upload", large, false, http.StatusOK},
+		{"/api/upload", large, true, http.StatusOK},
+		{"/api/upload", `{"a": "` + strings.Repeat("x", 2000) + `"}`, false, http.StatusRequestEntityTooLarge},
+		{"/api/upload", `{"a": "` + strings.Repeat("x", 2000) + `"}`, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		w := postJSON(e, tc.path, tc.body, tc.chunked)
+		if w.Code != tc.status {
+			t.Errorf("%s len=%d chunked=%v: status = %d, want %d", tc.path, len(tc.body), tc.chunked, w.Code, tc.status)
+		}
+		if w.Code == http.StatusRequestEntityTooLarge && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+			t.Errorf("%s: Content-Type = %q", tc.path, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestBindJSONStreamMaxBodySize(t *testing.T) {
+	e := newTestEngine()
+	e.MaxBodySize = 64
+	type item struct {
+		N int `json:"n"`
+	}
+	var seen int
+	var streamErr error
+	e.Group("api").Post("/import", func(c *Context) {
+		streamErr = c.BindJSONStream(&item{}, func(elem any) error {
+			seen++
+			return nil
+		})
+		if streamErr != nil {
+			_ = c.FailValidation(streamErr)
+		}
+	})
+	elems := make([]string, 100)
+	for i := range elems {
+		elems[i] = `{"n": 1}`
+	}
+	w := postJSON(e, "/api/import", "["+strings.Join(elems, ",")+"]", true)
+	// 读到限制时停止 已经解码的元素已经交给了 fn
+	var maxBytesError *http.MaxBytesError
+	if !errors.As(streamErr, &maxBytesError) {
+		t.Fatalf("err = %v, want MaxBytesError", streamErr)
+	}
+	if seen == 0 || seen >= 10 {
+		t.Fatalf("decoded %d elements before the limit", seen)
+	}
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d", w.Code)
+	}
+}
+
+// countingBody 记录请求体被读取了多少次
+type countingBody struct {
+	*strings.Reader
+	reads int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	b.reads++
+	return b.Reader.Read(p)
+}
+
+func (b *countingBody) Close() error { return nil }
+
+func TestShouldBindBodyWithReusesBody(t *testing.T) {
+	body := &countingBody{Reader: strings.NewReader(`{"name": "bob", "age": 20}`)}
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", "application/json")
+	c := newTestContext(httptest.NewRecorder(), r)
+	var first struct {
+		Name string `json:"name"`
+	}
+	var second struct {
+		Age int `json:"age"`
+	}
+	var whole map[string]any
+	if err := c.ShouldBindBodyWith(&first, binding.JSON); err != nil {
+		t.Fatal(err)
+	}
+	reads := body.reads
+	if err := c.ShouldBindBodyWith(&second, binding.JSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ShouldBindBodyWith(&whole, binding.JSON); err != nil {
+		t.Fatal(err)
+	}
+	if first.Name != "bob" || second.Age != 20 || whole["name"] != "bob" {
+		t.Fatalf("bound values: %+v %+v %v", first, second, whole)
+	}
+	// 之后的绑定使用缓存 不再读取请求体
+	if body.reads != reads {
+		t.Fatalf("body was read again: %d -> %d", reads, body.reads)
+	}
+	cached, err := c.BodyBytes()
+	if err != nil || string(cached) != `{"name": "bob", "age": 20}` {
+		t.Fatalf("BodyBytes = %q, %v", cached, err)
+	}
+}
+
+func TestShouldBindBodyWithCallerBinding(t *testing.T) {
+	var user struct {
+		Name string `json:"name"`
+	}
+	newContext := func() *Context {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "bob", "admin": true}`))
+		r.Header.Set("Content-Type", "application/json")
+		return newTestContext(httptest.NewRecorder(), r)
+	}
+	// 调用方的设置不会被 Context 的设置覆盖
+	strict := binding.JSON
+	strict.DisallowUnknownFields = true
+	if err := newContext().ShouldBindBodyWith(&user, strict); err == nil {
+		t.Fatal("unknown field was accepted")
+	}
+	// 默认的 binding.JSON 使用 Context 的设置
+	c := newContext()
+	if err := c.ShouldBindBodyWith(&user, binding.JSON); err != nil || user.Name != "bob" {
+		t.Fatalf("err = %v user = %+v", err, user)
+	}
+	c = newContext()
+	c.DisallowUnknownFields = true
+	if err := c.ShouldBindBodyWith(&user, binding.JSON); err == nil {
+		t.Fatal("unknown field was accepted")
+	}
+}
+
+func TestBodyBytesMaxBodySize(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "`+strings.Repeat("x", 100)+`"}`))
+	w := httptest.NewRecorder()
+	c := newTestContext(w, r)
+	c.SetMaxBodySize(16)
+	var m map[string]any
+	err := c.BindBodyWith(&m, binding.JSON)
+	var bindErr *BindError
+	if !isBodyTooLarge(err) || !errors.As(err, &bindErr) || bindErr.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("err = %v, want MaxBytesError", err)
+	}
+	// 超出限制时不缓存 再次绑定同样失败
+	if c.bodyBytes != nil {
+		t.Fatalf("partial body was cached: %q", c.bodyBytes)
+	}
+	if err = c.ShouldBindBodyWith(&m, binding.JSON); !isBodyTooLarge(err) {
+		t.Fatalf("second bind: err = %v", err)
+	}
+	// 绑定时不写入响应 由 ErrorHandler 生成413
+	if c.Written() {
+		t.Fatal("response was written while binding")
+	}
+	c.handleErrors()
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "request body too large") {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,426 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gitbuh.com/spxzx/spxgo"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTTL = time.Minute
+
+	// 响应头 X-Cache 的值
+	hit   = "HIT"
+	miss  = "MISS"
+	stale = "STALE"
+)
+
+type Options struct {
+	Store Store         // 默认为64M的 MemoryStore
+	TTL   time.Duration // 缓存的有效期 默认1分钟
+	// StaleWhileRevalidate 过期后这段时间内直接返回旧内容 同时在后台刷新缓存
+	StaleWhileRevalidate time.Duration
+	// Headers 参与缓存键的请求头 比如 Accept-Language Accept-Encoding Host 总是在键中 不需要加到这里
+	// 带有 Authorization 或 Cookie 的请求默认不使用缓存 把它们加到这里后按用户分别缓存
+	// 响应的 Vary 中有不在这里的请求头时不缓存 比如 Compress 设置的 Vary: Accept-Encoding
+	Headers []string
+}
+
+// privateHeaders 带有这些请求头的响应通常因用户而异
+var privateHeaders = []string{"Authorization", "Cookie"}
+
+// entry 缓存的一个响应
+type entry struct {
+	Status    int
+	Header    http.Header
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time // 在此之前为新鲜内容
+}
+
+type middleware struct {
+	Options
+	group      group
+	mutex      sync.Mutex
+	refreshing map[string]bool // 正在刷新的缓存键 同一个键只刷新一次
+	keyed      map[string]bool // Headers 中的请求头
+}
+
+// Middleware 缓存GET和HEAD请求的200响应 键为请求方法 Host 路径 url参数和 Options.Headers 中的请求头
+// 同一个键同时未命中时只执行一次处理函数 其它请求等待结果
+// 设置了cookie或者 Cache-Control 为 private no-store no-cache 的响应 以及流式响应不缓存
+// 带有 Authorization 或 Cookie 且它们不在 Options.Headers 中的请求 以及协议升级请求直接执行处理函数
+func Middleware(opts Options) spxgo.MiddlewareFunc {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore(0)
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	// Host 不在 r.Header 中 总是写入键 Vary: Host 也可以缓存
+	m := &middleware{Options: opts, refreshing: make(map[string]bool), keyed: map[string]bool{"Host": true}}
+	for _, name := range opts.Headers {
+		m.keyed[http.CanonicalHeaderKey(name)] = true
+	}
+	return func(next spxgo.HandlerFunc) spxgo.HandlerFunc {
+		return func(c *spxgo.Context) {
+			if (c.R.Method != http.MethodGet && c.R.Method != http.MethodHead) || m.private(c.R) || isUpgrade(c.R) {
+				next(c)
+				return
+			}
+			m.serve(c, next)
+		}
+	}
+}
+
+func (m *middleware) serve(c *spxgo.Context, next spxgo.HandlerFunc) {
+	key := m.key(c.R)
+	if e := m.load(c, key); e != nil {
+		now := time.Now()
+		if now.Before(e.ExpiresAt) {
+			writeEntry(c, e, hit)
+			return
+		}
+		if now.Before(e.ExpiresAt.Add(m.StaleWhileRevalidate)) {
+			writeEntry(c, e, stale)
+			m.revalidate(c, next, key)
+			return
+		}
+	}
+	e, leader, err := m.group.do(c.R.Context(), key, func() *entry {
+		return m.fill(c, next, key)
+	})
+	if leader || err != nil {
+		// 等待时客户端已经断开 不需要再写入
+		return
+	}
+	if e == nil {
+		// 结果不能共享 比如带有cookie 自己执行
+		next(c)
+		return
+	}
+	writeEntry(c, e, hit)
+}
+
+// private 请求带有不参与缓存键的 Authorization 或 Cookie
+func (m *middleware) private(r *http.Request) bool {
+	for _, name := range privateHeaders {
+		if !m.keyed[name] && r.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isUpgrade websocket 等协议升级请求 不能合并到一次处理函数中执行
+func isUpgrade(r *http.Request) bool {
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// varyKeyed 响应的 Vary 中的请求头都参与了缓存键 Vary: * 总是 false
+func (m *middleware) varyKeyed(header http.Header) bool {
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" || !m.keyed[http.CanonicalHeaderKey(name)] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// key 对请求方法 Host 路径 排序后的url参数和指定请求头做摘要
+// 服务端会把 Host 从 r.Header 中移到 r.Host 同一个服务多个域名时不同域名的响应不能混用
+func (m *middleware) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte('\n')
+	b.WriteString(strings.ToLower(r.Host))
+	b.WriteString(r.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(r.URL.Query().Encode())
+	for _, name := range m.Headers {
+		b.WriteByte('\n')
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *middleware) load(c *spxgo.Context, key string) *entry {
+	data, ok, err := m.Store.Load(key)
+	if err != nil {
+		m.logError(c, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var e entry
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		m.logError(c, err)
+		return nil
+	}
+	return &e
+}
+
+func (m *middleware) save(c *spxgo.Context, key string, e *entry) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		m.logError(c, err)
+		return
+	}
+	if err := m.Store.Save(key, buf.Bytes(), e.ExpiresAt.Add(m.StaleWhileRevalidate)); err != nil {
+		m.logError(c, err)
+	}
+}
+
+func (m *middleware) logError(c *spxgo.Context, err error) {
+	if c.Logger != nil {
+		c.Logger.Error(err)
+	}
+}
+
+// fill 未命中时执行处理函数 响应照常写给客户端 可以缓存时存入 Store
+func (m *middleware) fill(c *spxgo.Context, next spxgo.HandlerFunc, key string) *entry {
+	w := &recorder{ResponseWriter: c.W, header: c.W.Header()}
+	c.W = w
+	defer func() {
+		c.W = w.ResponseWriter
+	}()
+	w.Header().Set("X-Cache", miss)
+	next(c)
+	if w.streamed {
+		return nil
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+		m.logError(c, err)
+	}
+	e := m.entry(w)
+	if e != nil {
+		m.save(c, key, e)
+	}
+	return e
+}
+
+// revalidate 已经返回了旧内容 在后台用复制的 Context 重新执行处理函数 结果只用于更新缓存
+func (m *middleware) revalidate(c *spxgo.Context, next spxgo.HandlerFunc, key string) {
+	m.mutex.Lock()
+	if m.refreshing[key] {
+		m.mutex.Unlock()
+		return
+	}
+	m.refreshing[key] = true
+	m.mutex.Unlock()
+	// 请求结束后 c 会被复用 不能在 goroutine 中使用
+	cp := c.Copy()
+	w := &recorder{header: make(http.Header)}
+	cp.W = w
+	go func() {
+		defer func() {
+			m.mutex.Lock()
+			delete(m.refreshing, key)
+			m.mutex.Unlock()
+		}()
+		defer func() {
+			if err := recover(); err != nil {
+				m.logError(cp, fmt.Errorf("cache: revalidate %s panic: %v", cp.R.URL.Path, err))
+			}
+		}()
+		next(cp)
+		if e := m.entry(w); e != nil {
+			m.save(cp, key, e)
+		}
+	}()
+}
+
+// entry 判断响应是否可以缓存
+func (m *middleware) entry(w *recorder) *entry {
+	if w.streamed || (w.status != 0 && w.status != http.StatusOK) {
+		return nil
+	}
+	header := w.header.Clone()
+	if header.Get("Set-Cookie") != "" || !m.varyKeyed(header) {
+		return nil
+	}
+	cc := strings.ToLower(header.Get("Cache-Control"))
+	for _, directive := range []string{"private", "no-store", "no-cache"} {
+		if strings.Contains(cc, directive) {
+			return nil
+		}
+	}
+	header.Del("X-Cache")
+	now := time.Now()
+	return &entry{
+		Status:    http.StatusOK,
+		Header:    header,
+		Body:      append([]byte(nil), w.body.Bytes()...),
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.TTL),
+	}
+}
+
+func writeEntry(c *spxgo.Context, e *entry, state string) {
+	header := c.W.Header()
+	for k, v := range e.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Age", strconv.Itoa(int(time.Since(e.CreatedAt)/time.Second)))
+	header.Set("X-Cache", state)
+	header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+	c.W.WriteHeader(e.Status)
+	c.StatusCode = e.Status
+	if c.R.Method != http.MethodHead {
+		_, _ = c.W.Write(e.Body)
+	}
+}
+
+// recorder 缓冲处理函数的输出 Flush 或 Hijack 后变为直接写入且不缓存
+// ResponseWriter 为空时(刷新缓存)只记录不输出
+type recorder struct {
+	http.ResponseWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	streamed bool
+}
+
+func (w *recorder) Header() http.Header {
+	return w.header
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if w.streamed {
+		if w.ResponseWriter != nil {
+			w.ResponseWriter.WriteHeader(status)
+		}
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *recorder) Write(p []byte) (int, error) {
+	if w.streamed {
+		if w.ResponseWriter == nil {
+			return len(p), nil
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+// stream 不再缓冲 写出已经缓冲的内容
+func (w *recorder) stream() error {
+	if w.streamed {
+		return nil
+	}
+	w.streamed = true
+	if w.ResponseWriter == nil {
+		return nil
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+	return err
+}
+
+func (w *recorder) Flush() {
+	if err := w.stream(); err != nil {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Written 内容还缓存在这里时也算已经写入 见 spxgo.Context.Written
+func (w *recorder) Written() bool {
+	return w.status != 0 || w.streamed
+}
+
+func (w *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not supported")
+	}
+	w.streamed = true
+	return hijacker.Hijack()
+}
+
+// call 正在执行的一次未命中
+type call struct {
+	done  chan struct{}
+	entry *entry
+}
+
+// group 合并同一个键同时发生的未命中
+type group struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+// do 第一个请求执行 fn 其它请求等待它的结果 leader 表示是否由自己执行
+// 等待的请求在自己的 ctx 取消时不再等待 返回 ctx 的错误
+func (g *group) do(ctx context.Context, key string, fn func() *entry) (e *entry, leader bool, err error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if cl, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		select {
+		case <-cl.done:
+			return cl.entry, false, nil
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	cl := &call{done: make(chan struct{})}
+	g.calls[key] = cl
+	g.mutex.Unlock()
+	// fn 发生 panic 时也要放开等待的请求 它们拿到 nil 后自己执行
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(cl.done)
+	}()
+	cl.entry = fn()
+	return cl.entry, true, nil
+}
@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"context"
+	"gitbuh.com/spxzx/spxgo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestEngine 不带日志等中间件 只有 GET /c/x 一个缓存的路由
+func newTestEngine(opts Options, handler spxgo.HandlerFunc) *spxgo.Engine {
+	e := spxgo.New()
+	g := e.Group("c")
+	g.Use(Middleware(opts))
+	g.Get("/x", handler)
+	return e
+}
+
+func do(e *spxgo.Engine, target string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareHit(t *testing.T) {
+	var calls int32
+	e := newTestEngine(Options{Headers: []string{"Accept-Language"}}, func(c *spxgo.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.W.Header().Set("X-Call", string(rune('0'+n)))
+		_ = c.String(http.StatusOK, "hello %s", c.R.URL.Query().Get("name"))
+	})
+	w := do(e, "/c/x?name=a", nil)
+	if w.Header().Get("X-Cache") != miss || w.Body.String() != "hello a" {
+		t.Fatalf("first request: X-Cache=%q body=%q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+	w = do(e, "/c/x?name=a", nil)
+	if w.Header().Get("X-Cache") != hit || w.Body.String() != "hello a" || w.Header().Get("X-Call") != "1" {
+		t.Fatalf("second request: X-Cache=%q body=%q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+	if w.Header().Get("Age") == "" || w.Header().Get("Content-Length") != "7" {
+		t.Fatalf("Age=%q Content-Length=%q", w.Header().Get("Age"), w.Header().Get("Content-Length"))
+	}
+	// url参数和 Headers 中的请求头是键的一部分
+	if w = do(e, "/c/x?name=b", nil); w.Header().Get("X-Cache") != miss {
+		t.Fatal("different query should miss")
+	}
+	if w = do(e, "/c/x?name=a", map[string]string{"Accept-Language": "zh"}); w.Header().Get("X-Cache") != miss {
+		t.Fatal("different Accept-Language should miss")
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("handler called %d times, want 3", n)
+	}
+}
+
+func TestMiddlewareHost(t *testing.T) {
+	var calls int32
+	e := newTestEngine(Options{}, func(c *spxgo.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.W.Header().Set("Vary", "Host")
+		_ = c.String(http.StatusOK, "host %s", c.R.Host)
+	})
+	// 同一个路径不同域名分别缓存
+	for _, host := range []string{"a.example.com", "b.example.com", "a.example.com", "B.example.com"} {
+		r := httptest.NewRequest(http.MethodGet, "/c/x", nil)
+		r.Host = host
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, r)
+		if want := "host " + host; w.Body.String() != want && !strings.EqualFold(w.Body.String(), want) {
+			t.Fatalf("Host %s: body = %q", host, w.Body.String())
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("handler called %d times, want 2", n)
+	}
+}
+
+func TestMiddlewareNotCached(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler spxgo.HandlerFunc
+	}{
+		{"cookie", func(c *spxgo.Context) {
+			c.SetCookie("a", "b", 0, "", "", false, false)
+			_ = c.String(http.StatusOK, "ok")
+		}},
+		{"private", func(c *spxgo.Context) {
+			c.SetCacheControl(spxgo.CacheControl{Private: true})
+			_ = c.String(http.StatusOK, "ok")
+		}},
+		{"status", func(c *spxgo.Context) {
+			_ = c.String(http.StatusNotFound, "ok")
+		}},
+		{"stream", func(c *spxgo.Context) {
+			_, _ = c.W.Write([]byte("o"))
+			c.W.(http.Flusher).Flush()
+			_, _ = c.W.Write([]byte("k"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			e := newTestEngine(Options{}, func(c *spxgo.Context) {
+				atomic.AddInt32(&calls, 1)
+				tt.handler(c)
+			})
+			for i := 0; i < 2; i++ {
+				if w := do(e, "/c/x", nil); w.Body.String() != "ok" || w.Header().Get("X-Cache") == hit {
+					t.Fatalf("body=%q X-Cache=%q", w.Body.String(), w.Header().Get("X-Cache"))
+				}
+			}
+			if n := atomic.LoadInt32(&calls); n != 2 {
+				t.Fatalf("handler called %d times, want 2", n)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePrivateRequest(t *testing.T) {
+	var calls int32
+	handler := func(c *spxgo.Context) {
+		atomic.AddInt32(&calls, 1)
+		_ = c.String(http.StatusOK, "user %s", c.R.Header.Get("Authorization")+c.R.Header.Get("Cookie"))
+	}
+	e := newTestEngine(Options{}, handler)
+	// 不同用户的响应不能互相看到
+	for _, header := range []map[string]string{{"Authorization": "Bearer a"}, {"Cookie": "sid=a"}} {
+		for i := 0; i < 2; i++ {
+			if w := do(e, "/c/x", header); w.Header().Get("X-Cache") != "" {
+				t.Fatalf("private request used cache: %v %q", header, w.Header().Get("X-Cache"))
+			}
+		}
+	}
+	if w := do(e, "/c/x", nil); w.Header().Get("X-Cache") != miss || w.Body.String() != "user " {
+		t.Fatalf("X-Cache=%q body=%q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	// Cookie 参与缓存键时按用户分别缓存
+	e = newTestEngine(Options{Headers: []string{"cookie"}}, handler)
+	do(e, "/c/x", map[string]string{"Cookie": "sid=a"})
+	if w := do(e, "/c/x", map[string]string{"Cookie": "sid=a"}); w.Header().Get("X-Cache") != hit || w.Body.String() != "user sid=a" {
+		t.Fatalf("X-Cache=%q body=%q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+	if w := do(e, "/c/x", map[string]string{"Cookie": "sid=b"}); w.Header().Get("X-Cache") != miss || w.Body.String() != "user sid=b" {
+		t.Fatalf("X-Cache=%q body=%q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+}
+
+func TestMiddlewareStaleWhileRevalidate(t *testing.T) {
+	var version int32
+	release := make(chan struct{})
+	e := newTestEngine(Options{TTL: 10 * time.Millisecond, StaleWhileRevalidate: time.Minute}, func(c *spxgo.Context) {
+		n := atomic.AddInt32(&version, 1)
+		if n > 1 {
+			// 刷新缓存时阻塞 请求不应该等待它
+			<-release
+		}
+		_ = c.String(http.StatusOK, "v%d", n)
+	})
+	do(e, "/c/x", nil)
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- do(e, "/c/x", nil)
+	}()
+	select {
+	case w := <-done:
+		if w.Header().Get("X-Cache") != stale || w.Body.String() != "v1" {
+			t.Fatalf("X-Cache=%q body=%q", w.Header().Get("X-Cache"), w.Body.String())
+		}
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("stale response waited for revalidation")
+	}
+	// 刷新中的键不会重复刷新
+	if w := do(e, "/c/x", nil); w.Header().Get("X-Cache") != stale {
+		t.Fatalf("X-Cache = %q", w.Header().Get("X-Cache"))
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		w := do(e, "/c/x", nil)
+		if w.Body.String() == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache was not revalidated: %q", w.Body.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&version); n != 2 {
+		t.Fatalf("handler called %d times, want 2", n)
+	}
+}
+
+func TestMiddlewareCoalesce(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+	e := newTestEngine(Options{}, func(c *spxgo.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		_ = c.String(http.StatusOK, "ok")
+	})
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = do(e, "/c/x", nil)
+	}()
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = do(e, "/c/x", nil)
+		}(i)
+	}
+
+	// 等待中的请求在自己的客户端断开后不再等待
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/c/x", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	canceled := make(chan struct{})
+	go func() {
+		e.ServeHTTP(w, r)
+		close(canceled)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("canceled follower kept waiting")
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("canceled follower wrote %q", w.Body.String())
+	}
+
+	close(release)
+	wg.Wait()
+	for i, w := range results {
+		if w.Body.String() != "ok" {
+			t.Fatalf("request %d: body=%q", i, w.Body.String())
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("handler called %d times, want 1", n)
+	}
+}
+
+func TestMiddlewareVary(t *testing.T) {
+	large := strings.Repeat("hello spxgo ", 200)
+	for _, opts := range []Options{{}, {Headers: []string{"Accept-Encoding"}}} {
+		e := spxgo.New()
+		g := e.Group("c")
+		// cache 在 Compress 外层 缓存的是压缩后的内容
+		g.Use(spxgo.Compress(spxgo.CompressConfig{}), Middleware(opts))
+		g.Get("/x", func(c *spxgo.Context) {
+			_ = c.String(http.StatusOK, large)
+		})
+		if w := do(e, "/c/x", map[string]string{"Accept-Encoding": "gzip"}); w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+		}
+		w := do(e, "/c/x", nil)
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+			t.Fatalf("%v: got gzip response without Accept-Encoding: X-Cache=%q", opts.Headers, w.Header().Get("X-Cache"))
+		}
+		if w.Header().Get("X-Cache") == hit {
+			t.Fatalf("%v: X-Cache = %q", opts.Headers, w.Header().Get("X-Cache"))
+		}
+		// Vary 中的请求头参与缓存键时按它分别缓存
+		w = do(e, "/c/x", map[string]string{"Accept-Encoding": "gzip"})
+		if want := map[bool]string{false: miss, true: hit}[len(opts.Headers) > 0]; w.Header().Get("X-Cache") != want {
+			t.Fatalf("%v: X-Cache = %q, want %q", opts.Headers, w.Header().Get("X-Cache"), want)
+		}
+	}
+
+	var calls int32
+	e := newTestEngine(Options{Headers: []string{"Accept-Language"}}, func(c *spxgo.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.W.Header().Set("Vary", "*")
+		_ = c.String(http.StatusOK, "ok")
+	})
+	do(e, "/c/x", nil)
+	if w := do(e, "/c/x", nil); w.Header().Get("X-Cache") == hit || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("Vary: * was cached: X-Cache=%q calls=%d", w.Header().Get("X-Cache"), calls)
+	}
+}
+
+func TestMiddlewareUpgrade(t *testing.T) {
+	var calls int32
+	e := newTestEngine(Options{}, func(c *spxgo.Context) {
+		atomic.AddInt32(&calls, 1)
+		_ = c.String(http.StatusOK, "ok")
+	})
+	header := map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket"}
+	for i := 0; i < 2; i++ {
+		if w := do(e, "/c/x", header); w.Header().Get("X-Cache") != "" {
+			t.Fatalf("upgrade request used cache: X-Cache=%q", w.Header().Get("X-Cache"))
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("handler called %d times, want 2", n)
+	}
+}
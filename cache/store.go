@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Store 缓存的存储 data 是编码后的响应
+// 过期的内容 Load 时应当当作不存在
+type Store interface {
+	Load(key string) (data []byte, ok bool, err error)
+	Save(key string, data []byte, expiresAt time.Time) error
+	Delete(key string) error
+}
+
+const defaultMaxBytes = 64 << 20 // 64M
+
+type memoryItem struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryStore 内存存储 总大小超过 maxBytes 时淘汰最久没有使用的内容
+type MemoryStore struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	size     int64
+	ll       *list.List // 最近使用的在前面
+	items    map[string]*list.Element
+}
+
+// NewMemoryStore maxBytes<=0 时使用64M
+func NewMemoryStore(maxBytes int64) *MemoryStore {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	return &MemoryStore{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (m *MemoryStore) Load(key string) ([]byte, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := e.Value.(*memoryItem)
+	if !time.Now().Before(item.expiresAt) {
+		m.remove(e)
+		return nil, false, nil
+	}
+	m.ll.MoveToFront(e)
+	return item.data, true, nil
+}
+
+func (m *MemoryStore) Save(key string, data []byte, expiresAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if e, ok := m.items[key]; ok {
+		m.remove(e)
+	}
+	size := itemSize(key, data)
+	if size > m.maxBytes {
+		return nil // 单个内容超过上限 不缓存
+	}
+	item := &memoryItem{key: key, data: append([]byte(nil), data...), expiresAt: expiresAt}
+	m.items[key] = m.ll.PushFront(item)
+	m.size += size
+	for m.size > m.maxBytes {
+		m.remove(m.ll.Back())
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if e, ok := m.items[key]; ok {
+		m.remove(e)
+	}
+	return nil
+}
+
+// Len 缓存的条数
+func (m *MemoryStore) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.ll.Len()
+}
+
+func (m *MemoryStore) remove(e *list.Element) {
+	item := m.ll.Remove(e).(*memoryItem)
+	delete(m.items, item.key)
+	m.size -= itemSize(item.key, item.data)
+}
+
+func itemSize(key string, data []byte) int64 {
+	return int64(len(key) + len(data))
+}
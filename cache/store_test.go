@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(0)
+	if _, ok, err := store.Load("a"); err != nil || ok {
+		t.Fatalf("load missing key: ok=%v err=%v", ok, err)
+	}
+	if err := store.Save("a", []byte("first"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	data, ok, err := store.Load("a")
+	if err != nil || !ok || !bytes.Equal(data, []byte("first")) {
+		t.Fatalf("load saved key: data=%q ok=%v err=%v", data, ok, err)
+	}
+	if err = store.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ = store.Load("a"); ok {
+		t.Fatal("deleted key still exists")
+	}
+	// 过期
+	if err = store.Save("a", []byte("expired"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ = store.Load("a"); ok {
+		t.Fatal("expired key still exists")
+	}
+}
+
+func TestMemoryStoreEvict(t *testing.T) {
+	// 每条 1+9 字节 最多放下两条
+	store := NewMemoryStore(20)
+	expires := time.Now().Add(time.Minute)
+	_ = store.Save("a", []byte("123456789"), expires)
+	_ = store.Save("b", []byte("123456789"), expires)
+	// 访问 a 之后 b 成为最久没有使用的
+	_, _, _ = store.Load("a")
+	_ = store.Save("c", []byte("123456789"), expires)
+	if _, ok, _ := store.Load("b"); ok {
+		t.Fatal("least recently used key was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := store.Load(key); !ok {
+			t.Fatalf("key %s was evicted", key)
+		}
+	}
+	if store.Len() != 2 {
+		t.Fatalf("len = %d, want 2", store.Len())
+	}
+}
@@ -0,0 +1,27 @@
+// Package gojson 基于 github.com/goccy/go-json 的 codec.JSONCodec 与 encoding/json 兼容 速度更快
+package gojson
+
+import (
+	"gitbuh.com/spxzx/spxgo/codec"
+	"github.com/goccy/go-json"
+	"io"
+)
+
+type Codec struct {
+}
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (Codec) MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+func (Codec) NewEncoder(w io.Writer) codec.JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (Codec) NewDecoder(r io.Reader) codec.JSONDecoder {
+	return json.NewDecoder(r)
+}
@@ -0,0 +1,102 @@
+package gojson
+
+import (
+	"bytes"
+	"gitbuh.com/spxzx/spxgo/codec"
+	"testing"
+)
+
+type address struct {
+	City   string `json:"city"`
+	Street string `json:"street"`
+	Zip    string `json:"zip"`
+}
+
+type user struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	Age       int               `json:"age"`
+	Admin     bool              `json:"admin"`
+	Tags      []string          `json:"tags"`
+	Addresses []address         `json:"addresses"`
+	Meta      map[string]string `json:"meta"`
+}
+
+var sample = user{
+	ID:    9007199254740993,
+	Name:  "spx",
+	Email: "spx@example.com",
+	Age:   18,
+	Tags:  []string{"go", "web", "json"},
+	Addresses: []address{
+		{City: "Beijing", Street: "Chang'an Avenue", Zip: "100000"},
+		{City: "Shanghai", Street: "Nanjing Road", Zip: "200000"},
+	},
+	Meta: map[string]string{"source": "benchmark", "lang": "zh"},
+}
+
+var codecs = []struct {
+	name  string
+	codec codec.JSONCodec
+}{
+	{"std", codec.StdJSON{}},
+	{"gojson", Codec{}},
+}
+
+// TestCompatible 两种实现的编码结果一致 可以互相解码
+func TestCompatible(t *testing.T) {
+	std, err := codec.StdJSON{}.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := Codec{}.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(std, fast) {
+		t.Fatalf("marshal mismatch:\n%s\n%s", std, fast)
+	}
+	var got user
+	if err = (Codec{}).NewDecoder(bytes.NewReader(std)).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != sample.ID || len(got.Addresses) != 2 || got.Meta["lang"] != "zh" {
+		t.Fatalf("decode mismatch: %+v", got)
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, c := range codecs {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.codec.Marshal(sample); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, _ := codec.StdJSON{}.Marshal(sample)
+	for _, c := range codecs {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var u user
+				if err := c.codec.NewDecoder(bytes.NewReader(data)).Decode(&u); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenDecoder(t *testing.T) {
+	// binding 的必填检查和流式绑定需要逐个读取 token
+	if _, ok := (Codec{}).NewDecoder(bytes.NewReader(nil)).(codec.JSONTokenDecoder); !ok {
+		t.Fatal("decoder does not implement codec.JSONTokenDecoder")
+	}
+}
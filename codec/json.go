@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSON binding 和 render 使用的json编解码 替换成其它实现时需要在启动时设置
+// 比如 codec.JSON = gojson.Codec{}
+var JSON JSONCodec = StdJSON{}
+
+// JSONCodec json编解码器 方法与 encoding/json 一致
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	MarshalIndent(v any, prefix, indent string) ([]byte, error)
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+type JSONEncoder interface {
+	Encode(v any) error
+	SetEscapeHTML(on bool)
+	SetIndent(prefix, indent string)
+}
+
+type JSONDecoder interface {
+	Decode(v any) error
+	UseNumber()
+	DisallowUnknownFields()
+}
+
+// JSONTokenDecoder 可以逐个读取 token 的解码器 encoding/json 和 gojson 的解码器都实现了
+// binding 的必填检查和流式绑定用它读取文档结构 NewDecoder 返回的解码器没有实现时使用 encoding/json
+type JSONTokenDecoder interface {
+	JSONDecoder
+	Token() (json.Token, error)
+	More() bool
+	InputOffset() int64
+}
+
+// StdJSON 标准库 encoding/json
+type StdJSON struct {
+}
+
+func (StdJSON) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdJSON) MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+func (StdJSON) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (StdJSON) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
@@ -0,0 +1,407 @@
+package spxgo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	defaultCompressMinSize = 1024
+)
+
+// defaultCompressTypes 以 / 结尾的按前缀匹配 以 + 开头的按后缀匹配 其它完全匹配
+var defaultCompressTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/x-javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+	"+json",
+	"+xml",
+}
+
+type CompressConfig struct {
+	MinSize      int      // 响应小于该字节数时不压缩 默认1024
+	ContentTypes []string // 允许压缩的 Content-Type 默认为文本 json js xml svg 等
+	Encodings    []string // 支持的编码 按优先级排列 默认 br gzip deflate
+	GzipLevel    int      // gzip 和 deflate 的压缩级别 -2到9 0 使用默认级别
+	BrotliLevel  int      // brotli 的压缩级别 0到11 0 使用默认级别
+}
+
+// Compress 按照 Accept-Encoding 压缩响应
+// HEAD请求 已经设置了 Content-Encoding 的响应 206 部分内容和 Cache-Control: no-transform 的响应不压缩
+// 压缩级别或者编码不支持时 panic 在启动时就能发现配置错误
+func Compress(conf CompressConfig) MiddlewareFunc {
+	if conf.GzipLevel < gzip.HuffmanOnly || conf.GzipLevel > gzip.BestCompression {
+		panic(fmt.Sprintf("spxgo: invalid CompressConfig.GzipLevel %d, must be between %d and %d",
+			conf.GzipLevel, gzip.HuffmanOnly, gzip.BestCompression))
+	}
+	if conf.BrotliLevel < brotli.BestSpeed || conf.BrotliLevel > brotli.BestCompression {
+		panic(fmt.Sprintf("spxgo: invalid CompressConfig.BrotliLevel %d, must be between %d and %d",
+			conf.BrotliLevel, brotli.BestSpeed, brotli.BestCompression))
+	}
+	for _, encoding := range conf.Encodings {
+		if encoding != EncodingBrotli && encoding != EncodingGzip && encoding != EncodingDeflate {
+			panic(fmt.Sprintf("spxgo: unsupported CompressConfig.Encodings %q", encoding))
+		}
+	}
+	if conf.MinSize <= 0 {
+		conf.MinSize = defaultCompressMinSize
+	}
+	if len(conf.ContentTypes) == 0 {
+		conf.ContentTypes = defaultCompressTypes
+	}
+	if len(conf.Encodings) == 0 {
+		conf.Encodings = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	}
+	pools := newCompressPools(conf)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.R.Method == http.MethodHead || c.WebSocket != nil || isWebSocketRequest(c.R) {
+				next(c)
+				return
+			}
+			w := &compressWriter{
+				ResponseWriter: c.W,
+				conf:           &conf,
+				pools:          pools,
+				encoding:       negotiateEncoding(c.R.Header.Get("Accept-Encoding"), conf.Encodings),
+			}
+			c.W = w
+			defer func() {
+				c.W = w.ResponseWriter
+				if err := recover(); err != nil {
+					// 丢弃还没有发出的内容 让 Recovery 可以重新写入响应
+					w.abort()
+					panic(err)
+				}
+			}()
+			next(c)
+			if err := w.close(); err != nil && c.Logger != nil {
+				c.Logger.Error(err)
+			}
+		}
+	}
+}
+
+func isWebSocketRequest(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// negotiateEncoding 选出客户端可以接受的 q 值最高的编码 相同时按照服务端的顺序
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if f, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				q = f
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+func compressible(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range allowed {
+		switch {
+		case strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t):
+			return true
+		case strings.HasPrefix(t, "+") && strings.HasSuffix(mediaType, t):
+			return true
+		case mediaType == t:
+			return true
+		}
+	}
+	return false
+}
+
+// compressor 压缩后的写入器 Flush 用于流式响应
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressPools 复用压缩器 创建压缩器需要分配较大的内存
+type compressPools map[string]*sync.Pool
+
+func newCompressPools(conf CompressConfig) compressPools {
+	gzipLevel := conf.GzipLevel
+	if gzipLevel == 0 {
+		gzipLevel = gzip.DefaultCompression
+	}
+	brotliLevel := conf.BrotliLevel
+	if brotliLevel == 0 {
+		brotliLevel = brotli.DefaultCompression
+	}
+	return compressPools{
+		// 级别已经在 Compress 中检查过 这里不会出错
+		EncodingGzip: {New: func() any {
+			w, err := gzip.NewWriterLevel(io.Discard, gzipLevel)
+			if err != nil {
+				panic(err)
+			}
+			return w
+		}},
+		// http 中的 deflate 为 zlib 格式
+		EncodingDeflate: {New: func() any {
+			w, err := zlib.NewWriterLevel(io.Discard, gzipLevel)
+			if err != nil {
+				panic(err)
+			}
+			return w
+		}},
+		EncodingBrotli: {New: func() any {
+			return brotli.NewWriterLevel(io.Discard, brotliLevel)
+		}},
+	}
+}
+
+const (
+	compressPending     = iota // 还没有决定是否压缩 缓存写入的内容
+	compressPassthrough        // 不压缩 直接写入
+	compressActive             // 压缩中
+)
+
+type compressWriter struct {
+	http.ResponseWriter
+	conf     *CompressConfig
+	pools    compressPools
+	encoding string // 协商出的编码 为空时不压缩
+	state    int
+	status   int
+	checked  bool // 已经根据响应头和第一次写入的内容检查过是否可以压缩
+	buf      bytes.Buffer
+	writer   compressor
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.state != compressPending || w.status != 0 {
+		if w.state == compressPassthrough {
+			w.ResponseWriter.WriteHeader(status)
+		}
+		return
+	}
+	w.status = status
+	if w.excluded() {
+		w.passthrough()
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	switch w.state {
+	case compressPassthrough:
+		return w.ResponseWriter.Write(p)
+	case compressActive:
+		return w.writer.Write(p)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.checked {
+		w.checked = true
+		if !w.check(p) {
+			if err := w.passthrough(); err != nil {
+				return 0, err
+			}
+			return w.ResponseWriter.Write(p)
+		}
+	}
+	w.buf.Write(p)
+	if w.buf.Len() >= w.conf.MinSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// excluded 只看状态码和响应头就可以确定不压缩
+func (w *compressWriter) excluded() bool {
+	header := w.Header()
+	return w.status < http.StatusOK ||
+		w.status == http.StatusNoContent ||
+		w.status == http.StatusNotModified ||
+		w.status == http.StatusPartialContent ||
+		header.Get("Content-Encoding") != "" ||
+		header.Get("Content-Range") != "" ||
+		strings.Contains(header.Get("Cache-Control"), "no-transform")
+}
+
+// check 第一次写入时检查是否需要缓存内容准备压缩 同时补上 Vary 和 Content-Type
+func (w *compressWriter) check(p []byte) bool {
+	if w.excluded() {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		// 压缩后 net/http 无法再根据内容猜测类型 这里先设置好
+		header.Set("Content-Type", http.DetectContentType(p))
+	}
+	if !compressible(header.Get("Content-Type"), w.conf.ContentTypes) {
+		return false
+	}
+	// 这类响应的内容会随 Accept-Encoding 变化 不管这次是否压缩都需要告诉缓存
+	addVary(header, "Accept-Encoding")
+	if w.encoding == "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < w.conf.MinSize {
+		return false
+	}
+	return true
+}
+
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+// start 开始压缩 写出响应头和已经缓存的内容
+func (w *compressWriter) start() error {
+	w.state = compressActive
+	header := w.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	// 压缩后的内容和原内容不是同一个字节序列 强ETag改为弱ETag
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.writer = w.pools[w.encoding].Get().(compressor)
+	w.writer.Reset(w.ResponseWriter)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.writer.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// passthrough 不压缩 写出响应头和已经缓存的内容
+func (w *compressWriter) passthrough() error {
+	w.state = compressPassthrough
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// Flush 流式响应(比如 SSE)不再等待 MinSize 立即决定是否压缩
+func (w *compressWriter) Flush() {
+	if w.state == compressPending {
+		var err error
+		if w.checked {
+			err = w.start()
+		} else {
+			err = w.passthrough()
+		}
+		if err != nil {
+			return
+		}
+	}
+	if w.state == compressActive {
+		if err := w.writer.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not supported")
+	}
+	w.state = compressPassthrough
+	return hijacker.Hijack()
+}
+
+// Written 内容还缓存在这里时也算已经写入
+func (w *compressWriter) Written() bool {
+	return w.status != 0 || w.state != compressPending
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close 处理函数返回后调用 内容不足 MinSize 时原样写出
+func (w *compressWriter) close() error {
+	switch w.state {
+	case compressPending:
+		return w.passthrough()
+	case compressActive:
+		err := w.writer.Close()
+		w.release()
+		return err
+	}
+	return nil
+}
+
+// abort 发生 panic 时丢弃缓存的内容 已经开始压缩的尽量结束
+func (w *compressWriter) abort() {
+	w.buf.Reset()
+	if w.state == compressActive {
+		_ = w.writer.Close()
+		w.release()
+	}
+}
+
+func (w *compressWriter) release() {
+	w.writer.Reset(io.Discard)
+	w.pools[w.encoding].Put(w.writer)
+	w.writer = nil
+}
@@ -0,0 +1,201 @@
+package spxgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, br", EncodingBrotli}, // q 相同时按照服务端的顺序
+		{"br;q=0.5, gzip", EncodingGzip},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", EncodingBrotli},
+		{"*;q=0.1, deflate", EncodingDeflate},
+		{"GZIP", EncodingGzip},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, supported); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestCompressMinSize(t *testing.T) {
+	large := strings.Repeat("hello spxgo ", 200)
+	var body string
+	e := newTestEngine()
+	e.Group("c").Any("/x", func(c *Context) {
+		c.W.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		c.W.Header().Set("ETag", `"v1"`)
+		// 分成小块写入 在达到 MinSize 之前先缓存
+		for i := 0; i < len(body); i += 100 {
+			end := i + 100
+			if end > len(body) {
+				end = len(body)
+			}
+			_, _ = c.W.Write([]byte(body[i:end]))
+		}
+	}, Compress(CompressConfig{}), Recovery)
+
+	body = "small"
+	w := serve(e, http.MethodGet, "/c/x", nil, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+		t.Fatalf("small response was compressed: %q %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Vary = %q", w.Header().Get("Vary"))
+	}
+
+	body = large
+	w = serve(e, http.MethodGet, "/c/x", nil, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+	}
+	if got := gunzip(t, w.Body.Bytes()); got != large {
+		t.Fatalf("decompressed body mismatch: %d bytes", len(got))
+	}
+	// 压缩后强ETag改为弱ETag
+	if w.Header().Get("ETag") != `W/"v1"` {
+		t.Fatalf("ETag = %q", w.Header().Get("ETag"))
+	}
+
+	// 客户端不支持时原样返回
+	w = serve(e, http.MethodGet, "/c/x", nil, nil)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+		t.Fatal("response was compressed without Accept-Encoding")
+	}
+}
+
+func TestCompressExcluded(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+	tests := []struct {
+		name   string
+		method string
+		set    func(h http.Header)
+		status int
+	}{
+		{"head", http.MethodHead, func(h http.Header) {}, http.StatusOK},
+		{"encoded", http.MethodGet, func(h http.Header) { h.Set("Content-Encoding", "br") }, http.StatusOK},
+		{"partial", http.MethodGet, func(h http.Header) { h.Set("Content-Range", "bytes 0-2047/4096") }, http.StatusPartialContent},
+		{"no-transform", http.MethodGet, func(h http.Header) { h.Set("Cache-Control", "public, no-transform") }, http.StatusOK},
+		{"image", http.MethodGet, func(h http.Header) { h.Set("Content-Type", "image/png") }, http.StatusOK},
+		{"content-length", http.MethodGet, func(h http.Header) { h.Set("Content-Length", "10") }, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine()
+			e.Group("c").Any("/x", func(c *Context) {
+				c.W.Header().Set("Content-Type", "text/plain")
+				tt.set(c.W.Header())
+				c.W.WriteHeader(tt.status)
+				if tt.name == "content-length" {
+					_, _ = c.W.Write([]byte("0123456789"))
+					return
+				}
+				_, _ = c.W.Write([]byte(large))
+			}, Compress(CompressConfig{}), Recovery)
+			w := serve(e, tt.method, "/c/x", nil, map[string]string{"Accept-Encoding": "gzip"})
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != "" && got != "br" {
+				t.Fatalf("Content-Encoding = %q", got)
+			}
+			if tt.method != http.MethodHead && tt.name != "content-length" && w.Body.String() != large {
+				t.Fatalf("body was changed: %d bytes", w.Body.Len())
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	e := newTestEngine()
+	e.Group("c").Any("/x", func(c *Context) {
+		c.W.Header().Set("Content-Type", "text/event-stream")
+		_, _ = c.W.Write([]byte("data: 1\n\n"))
+		// 不足 MinSize 也立即开始压缩
+		c.W.(http.Flusher).Flush()
+		_, _ = c.W.Write([]byte("data: 2\n\n"))
+	}, Compress(CompressConfig{}), Recovery)
+	w := serve(e, http.MethodGet, "/c/x", nil, map[string]string{"Accept-Encoding": "gzip"})
+	if !w.Flushed || w.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("flushed=%v Content-Encoding=%q", w.Flushed, w.Header().Get("Content-Encoding"))
+	}
+	if got := gunzip(t, w.Body.Bytes()); got != "data: 1\n\ndata: 2\n\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestCompressPanic(t *testing.T) {
+	e := newTestEngine()
+	e.Group("c").Any("/x", func(c *Context) {
+		c.W.Header().Set("Content-Type", "text/plain")
+		_, _ = c.W.Write([]byte("partial"))
+		panic("boom")
+	}, Compress(CompressConfig{}), Recovery)
+	w := serve(e, http.MethodGet, "/c/x", nil, map[string]string{"Accept-Encoding": "gzip"})
+	// 缓存的内容被丢弃 Recovery 可以写出 500
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "" || strings.Contains(w.Body.String(), "partial") {
+		t.Fatalf("buffered content was written: %q", w.Body.String())
+	}
+}
+
+func TestCompressInvalidConfig(t *testing.T) {
+	tests := []struct {
+		conf CompressConfig
+		want string
+	}{
+		{CompressConfig{GzipLevel: 10}, "GzipLevel"},
+		{CompressConfig{GzipLevel: -3}, "GzipLevel"},
+		{CompressConfig{BrotliLevel: 12}, "BrotliLevel"},
+		{CompressConfig{Encodings: []string{"zstd"}}, "zstd"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				rec := recover()
+				if msg, _ := rec.(string); !strings.Contains(msg, tt.want) {
+					t.Errorf("Compress(%+v) panic = %v, want %q", tt.conf, rec, tt.want)
+				}
+			}()
+			Compress(tt.conf)
+		}()
+	}
+	// 合法的级别可以正常创建压缩器
+	e := newTestEngine()
+	e.Group("c").Any("/x", func(c *Context) {
+		_ = c.String(http.StatusOK, strings.Repeat("x", 2048))
+	}, Compress(CompressConfig{GzipLevel: gzip.HuffmanOnly, BrotliLevel: 11}), Recovery)
+	for _, encoding := range []string{EncodingBrotli, EncodingGzip, EncodingDeflate} {
+		if w := serve(e, http.MethodGet, "/c/x", nil, map[string]string{"Accept-Encoding": encoding}); w.Header().Get("Content-Encoding") != encoding {
+			t.Errorf("Content-Encoding = %q, want %q", w.Header().Get("Content-Encoding"), encoding)
+		}
+	}
+}
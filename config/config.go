@@ -5,6 +5,7 @@ import (
 	spxLog "gitbuh.com/spxzx/spxgo/log"
 	"github.com/BurntSushi/toml"
 	"os"
+	"strings"
 )
 
 var Conf = &SpxConfig{
@@ -24,7 +25,9 @@ func init() {
 
 func loadToml() {
 	configFile := flag.String("conf", "conf/app.toml", "app default config file")
-	flag.Parse()
+	// 不能在 init 中调用 flag.Parse，使用者自己注册的参数以及 go test 的 -test.* 参数都会报错退出
+	// 这里只从命令行中取出 -conf 的值
+	*configFile = lookupArg(os.Args[1:], "conf", *configFile)
 	if _, err := os.Stat(*configFile); err != nil {
 		Conf.logger.Info("conf/app.toml file not load, because not exist")
 		return
@@ -35,3 +38,25 @@ func loadToml() {
 		return
 	}
 }
+
+// lookupArg 支持 -name value / --name value / -name=value / --name=value 四种写法
+// 不带 - 的是位置参数 不会当成 name
+func lookupArg(args []string, name, defaultValue string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, name+"=") {
+			return arg[len(name)+1:]
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestLookupArg(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-conf", "a.toml"}, "a.toml"},
+		{[]string{"--conf", "a.toml"}, "a.toml"},
+		{[]string{"-conf=a.toml"}, "a.toml"},
+		{[]string{"--conf=a.toml"}, "a.toml"},
+		{[]string{"-v", "-conf=a.toml", "serve"}, "a.toml"},
+		// 位置参数不是 -conf
+		{[]string{"conf", "a.toml"}, "default.toml"},
+		{[]string{"conf=a.toml"}, "default.toml"},
+		{[]string{"---conf", "a.toml"}, "default.toml"},
+		{[]string{"--", "-conf", "a.toml"}, "default.toml"},
+		{[]string{"-conf"}, "default.toml"},
+	}
+	for _, tt := range tests {
+		if got := lookupArg(tt.args, "conf", "default.toml"); got != tt.want {
+			t.Errorf("lookupArg(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package spxgo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"gitbuh.com/spxzx/spxgo/binding"
 	"gitbuh.com/spxzx/spxgo/internal/bytesconv"
 	spxLog "gitbuh.com/spxzx/spxgo/log"
 	"gitbuh.com/spxzx/spxgo/render"
+	"gitbuh.com/spxzx/spxgo/serror"
+	"gitbuh.com/spxzx/spxgo/websocket"
 	"html/template"
 	"io"
 	"log"
@@ -14,13 +17,10 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
-)
-
-const (
-	defaultMaxMemory       = 32 << 20 // 32M
-	defaultMultipartMemory = 32 << 20
+	"time"
 )
 
 type Context struct {
@@ -32,10 +32,96 @@ type Context struct {
 	StatusCode            int
 	DisallowUnknownFields bool // 开启结构体中没有该属性 没有就报错 ! 但是如果传来的参数中有结构体中也没有的 _不会报错_ !
 	IsValidate            bool // 开启传参中含有结构体中没有的属性的报错
+	UseNumber             bool // json中的数字解码到 any 时使用 json.Number 而不是 float64
 	Logger                *spxLog.Logger
 	Keys                  map[string]any // 认证信息
 	mutex                 sync.RWMutex
-	sameSite              http.SameSite // 为了做安全性操作
+	sameSite              http.SameSite     // 为了做安全性操作
+	WebSocket             *websocket.Conn   // 通过 routerGroup.WebSocket 注册的路由握手成功后才有值
+	params                map[string]string // 路径参数 /get/:id
+	body                  io.ReadCloser     // 原始的请求体 SetMaxBodySize 在它上面重新限制大小
+	bodyBytes             []byte            // BodyBytes 读出的请求体 多次绑定时复用
+	errs                  Errors            // Context.Error 记录的错误
+	errsHandled           int               // 已经交给 ErrorHandler 处理过的错误个数
+	errorCheck            *serror.SpxError  // ErrorCheck 返回的当前请求的实例
+	writer                responseWriter    // ServeHTTP 中包装的 ResponseWriter 记录是否已经写入了响应
+}
+
+// reset Context 是从 pool 中复用的，需要清空上一个请求留下的状态
+func (c *Context) reset() {
+	c.StatusCode = 0
+	c.Keys = nil // 认证信息和会话不能带到下一个请求
+	c.WebSocket = nil
+	c.params = nil
+	c.body = nil
+	c.bodyBytes = nil
+	c.errs = nil
+	c.errsHandled = 0
+	c.errorCheck = nil
+}
+
+type contextKey struct{}
+
+// FromContext 取出 ServeHTTP 放入请求 Context 中的 *Context 比如在 binding.RegisterValidationCtx 注册的规则中
+// Context 会被复用 不要在请求结束后使用
+func FromContext(ctx context.Context) (*Context, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Context)
+	return c, ok
+}
+
+// detachedContext 保留请求 Context 中的值 但不会随客户端断开而取消
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Copy 复制一个不会被复用的 Context 用于请求结束后还在运行的 goroutine
+// 请求体不会复制 W 为空 需要调用方设置
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		engine:                c.engine,
+		DisallowUnknownFields: c.DisallowUnknownFields,
+		IsValidate:            c.IsValidate,
+		UseNumber:             c.UseNumber,
+		Logger:                c.Logger,
+		sameSite:              c.sameSite,
+		body:                  http.NoBody,
+	}
+	c.mutex.RLock()
+	if c.Keys != nil {
+		cp.Keys = make(map[string]any, len(c.Keys))
+		for k, v := range c.Keys {
+			cp.Keys[k] = v
+		}
+	}
+	c.mutex.RUnlock()
+	if c.params != nil {
+		cp.params = make(map[string]string, len(c.params))
+		for k, v := range c.params {
+			cp.params[k] = v
+		}
+	}
+	r := c.R.Clone(context.WithValue(detachedContext{c.R.Context()}, contextKey{}, cp))
+	r.Body = http.NoBody
+	r.ContentLength = 0
+	cp.R = r
+	return cp
+}
+
+// Param 路径参数 路由 /get/:id 请求 /get/1 时 Param("id") 返回 "1"
+func (c *Context) Param(key string) string {
+	return c.params[key]
 }
 
 func (c *Context) Set(key string, value any) {
@@ -85,6 +171,13 @@ func (c *Context) Render(statusCode int, r render.Render) error {
 	// 如果设置了 statusCode 对header的修改就不生效了。。。
 	// c.W.WriteHeader(statusCode)
 	err := r.Render(c.W, statusCode)
+	var encodeError *render.EncodeError
+	if errors.As(err, &encodeError) {
+		// 编码失败时还没有写入任何内容 改为返回500
+		http.Error(c.W, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		c.StatusCode = http.StatusInternalServerError
+		return err
+	}
 	c.StatusCode = statusCode
 	return err
 }
@@ -127,23 +220,70 @@ func (c *Context) HTMLTemplateGlob(name string, data any, pattern string) error
 
 // endregion
 
+// Template 使用 Engine.HTMLRenderer 渲染模板 没有设置时使用 Engine.HTMLRender 中的模板
+// 模板不存在或者执行失败时返回500
 func (c *Context) Template(status int, name string, data any) error {
-	return c.Render(status, &render.HTML{
-		Name:       name,
-		Data:       data,
-		Template:   c.engine.HTMLRender.Template,
-		IsTemplate: true,
-	})
+	if c.engine.HTMLRenderer != nil {
+		return c.Render(status, c.engine.HTMLRenderer.Instance(name, data))
+	}
+	return c.Render(status, &render.HTML{Name: name, Data: data, Template: c.engine.HTMLRender.Template, IsTemplate: true})
 }
 
 func (c *Context) JSON(status int, data any) error {
 	return c.Render(status, &render.JSON{Data: data})
 }
 
+// IndentedJSON 带缩进的json 比较耗费带宽 建议只在调试时使用
+func (c *Context) IndentedJSON(status int, data any) error {
+	return c.Render(status, &render.IndentedJSON{Data: data})
+}
+
+// SecureJSON 数据为数组时加上 while(1); 前缀
+func (c *Context) SecureJSON(status int, data any) error {
+	return c.Render(status, &render.SecureJSON{Data: data})
+}
+
+// AsciiJSON 非ASCII字符转成 \uXXXX
+func (c *Context) AsciiJSON(status int, data any) error {
+	return c.Render(status, &render.AsciiJSON{Data: data})
+}
+
+// PureJSON 不转义 < > & 等HTML字符
+func (c *Context) PureJSON(status int, data any) error {
+	return c.Render(status, &render.PureJSON{Data: data})
+}
+
+// JSONP 回调函数名取自url参数 callback 没有时返回普通json 不合法时返回400
+func (c *Context) JSONP(status int, data any) error {
+	callback := c.R.URL.Query().Get("callback")
+	if callback != "" && !render.ValidCallback(callback) {
+		c.Fail(http.StatusBadRequest, "invalid callback")
+		return render.ErrInvalidCallback
+	}
+	return c.Render(status, &render.JSONP{Callback: callback, Data: data})
+}
+
 func (c *Context) XML(status int, data any) error {
 	return c.Render(status, &render.XML{Data: data})
 }
 
+// ProtoBuf data 必须是 proto.Message
+func (c *Context) ProtoBuf(status int, data any) error {
+	return c.Render(status, &render.ProtoBuf{Data: data})
+}
+
+func (c *Context) MsgPack(status int, data any) error {
+	return c.Render(status, &render.MsgPack{Data: data})
+}
+
+func (c *Context) YAML(status int, data any) error {
+	return c.Render(status, &render.YAML{Data: data})
+}
+
+func (c *Context) TOML(status int, data any) error {
+	return c.Render(status, &render.TOML{Data: data})
+}
+
 // Redirect 简单重定向
 func (c *Context) Redirect(statusCode int, location string) error {
 	return c.Render(statusCode, &render.Redirect{
@@ -260,7 +400,7 @@ func (c *Context) QueryMap(key string) map[string]string {
 
 func (c *Context) initPostFormCache() {
 	if c.R != nil {
-		if err := c.R.ParseMultipartForm(defaultMaxMemory); err != nil {
+		if err := c.R.ParseMultipartForm(c.multipartMemory()); err != nil {
 			if !errors.Is(err, http.ErrNotMultipart) {
 				c.Logger.Info(err)
 			}
@@ -299,8 +439,17 @@ func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
 	return c.get(c.postFormCache, key)
 }
 
+// multipartMemory ParseMultipartForm 使用的内存上限 Engine.MaxMultipartMemory 为0时使用32M
+// 请求体同样受 MaxBodySize 限制 大文件请使用不落临时文件的 UploadReader
+func (c *Context) multipartMemory() int64 {
+	if c.engine != nil && c.engine.MaxMultipartMemory > 0 {
+		return c.engine.MaxMultipartMemory
+	}
+	return binding.DefaultMultipartMemory
+}
+
 func (c *Context) MultipartFormFiles() (*multipart.Form, error) {
-	err := c.R.ParseMultipartForm(defaultMultipartMemory)
+	err := c.R.ParseMultipartForm(c.multipartMemory())
 	return c.R.MultipartForm, err // MultipartForm 是一个Form结构体 .File 是具体的文件map
 }
 
@@ -329,6 +478,9 @@ func (c *Context) FormFiles(key string) []*multipart.FileHeader {
 	return multipartForm.File[key]
 }
 
+// SaveUploadFile 把上传的文件保存到 dst dst 由服务端决定 原样使用
+// 不要把客户端传来的 file.Filename 直接拼接到 dst 中 需要先经过 SanitizeFilename 清洗
+// 或者使用 SaveUploadFileTo
 func (c *Context) SaveUploadFile(file *multipart.FileHeader, dst string) error {
 	src, err := file.Open()
 	if err != nil {
@@ -356,7 +508,8 @@ func (c *Context) SaveUploadFile(file *multipart.FileHeader, dst string) error {
 
 func (c *Context) SaveAllUploadFiles(files []*multipart.FileHeader, dst string) error {
 	for _, file := range files {
-		err := c.SaveUploadFile(file, dst+file.Filename)
+		// 客户端传来的文件名不可信 需要清洗后再拼接
+		err := c.SaveUploadFile(file, filepath.Join(dst, SanitizeFilename(file.Filename)))
 		if err != nil {
 			log.Println(err)
 			return err
@@ -365,10 +518,10 @@ func (c *Context) SaveAllUploadFiles(files []*multipart.FileHeader, dst string)
 	return nil
 }
 
+// MustBindWith 同 ShouldBindWith 失败时返回 *BindError 并记录到 Context.Error 处理函数返回后由 ErrorHandler 生成响应
 func (c *Context) MustBindWith(obj any, bind binding.Binding) error {
 	if err := c.ShouldBindWith(obj, bind); err != nil {
-		c.W.WriteHeader(http.StatusBadRequest)
-		return err
+		return c.bindFail(err)
 	}
 	return nil
 }
@@ -377,18 +530,116 @@ func (c *Context) ShouldBindWith(obj any, bind binding.Binding) error {
 	return bind.Bind(c.R, obj)
 }
 
-// BindJson 解析传参中的json数据
-func (c *Context) BindJson(obj any) error {
+// jsonBinding 带上 Context 中对json解析的设置
+func (c *Context) jsonBinding() binding.Binding {
 	json := binding.JSON
 	json.IsValidate = c.IsValidate
 	json.DisallowUnknownFields = c.DisallowUnknownFields
-	return c.MustBindWith(obj, json)
+	json.UseNumber = c.UseNumber
+	return json
+}
+
+// formBinding 带上 Engine.MaxMultipartMemory
+func (c *Context) formBinding() binding.Binding {
+	form := binding.Form
+	form.MaxMemory = c.multipartMemory()
+	return form
+}
+
+func (c *Context) formMultipartBinding() binding.Binding {
+	form := binding.FormMultipart
+	form.MaxMemory = c.multipartMemory()
+	return form
+}
+
+// BindJson 解析传参中的json数据
+func (c *Context) BindJson(obj any) error {
+	return c.MustBindWith(obj, c.jsonBinding())
+}
+
+// ShouldBind 根据请求方法和 Content-Type 自动选择绑定器
+// 不支持的 Content-Type 返回 binding.ErrUnsupportedMediaType
+func (c *Context) ShouldBind(obj any) error {
+	b, err := binding.Default(c.R.Method, c.R.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	switch b.Name() {
+	case binding.JSON.Name():
+		b = c.jsonBinding()
+	case binding.Form.Name():
+		b = c.formBinding()
+	case binding.FormMultipart.Name():
+		b = c.formMultipartBinding()
+	}
+	return c.ShouldBindWith(obj, b)
+}
+
+// Bind 同 ShouldBind 失败时同 MustBindWith
+// 默认的 ErrorHandler 请求体过大返回413 不支持的 Content-Type 返回415 校验失败返回422 其它错误返回400
+func (c *Context) Bind(obj any) error {
+	if err := c.ShouldBind(obj); err != nil {
+		return c.bindFail(err)
+	}
+	return nil
 }
 
 func (c *Context) BindXML(obj any) error {
 	return c.MustBindWith(obj, binding.XML)
 }
 
+// BindProtoBuf 解析protobuf obj 必须是 proto.Message
+func (c *Context) BindProtoBuf(obj any) error {
+	return c.MustBindWith(obj, binding.ProtoBuf)
+}
+
+func (c *Context) BindMsgPack(obj any) error {
+	return c.MustBindWith(obj, binding.MsgPack)
+}
+
+func (c *Context) BindYAML(obj any) error {
+	return c.MustBindWith(obj, binding.YAML)
+}
+
+func (c *Context) BindTOML(obj any) error {
+	return c.MustBindWith(obj, binding.TOML)
+}
+
+// BindUri 解析路径参数 属性使用 `uri:"..."` 标记
+func (c *Context) BindUri(obj any) error {
+	return c.MustBindWith(obj, binding.Uri)
+}
+
+// BindHeader 解析请求头 属性使用 `header:"..."` 标记
+func (c *Context) BindHeader(obj any) error {
+	return c.MustBindWith(obj, binding.Header)
+}
+
+// BindCookie 解析cookie 属性使用 `cookie:"..."` 标记
+func (c *Context) BindCookie(obj any) error {
+	return c.MustBindWith(obj, binding.Cookie)
+}
+
+// ShouldBindAll 一次绑定路径参数、url参数、请求头和cookie 最后统一校验
+func (c *Context) ShouldBindAll(obj any) error {
+	return binding.BindAll(c.R, obj, binding.Uri, binding.Query, binding.Header, binding.Cookie)
+}
+
+// BindQuery 解析url参数 属性使用 `form:"..."` 标记
+func (c *Context) BindQuery(obj any) error {
+	return c.MustBindWith(obj, binding.Query)
+}
+
+// BindForm 解析url参数和表单
+func (c *Context) BindForm(obj any) error {
+	return c.MustBindWith(obj, c.formBinding())
+}
+
+// BindFormMultipart 解析multipart表单 包括上传的文件
+func (c *Context) BindFormMultipart(obj any) error {
+	return c.MustBindWith(obj, c.formMultipartBinding())
+}
+
 // ======================
 
 func (c *Context) Fail(statusCode int, msg string) {
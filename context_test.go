@@ -0,0 +1,180 @@
+package spxgo
+
+import (
+	"errors"
+	"gitbuh.com/spxzx/spxgo/binding"
+	"gitbuh.com/spxzx/spxgo/render"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestShouldBindAll(t *testing.T) {
+	type request struct {
+		Id      int    `uri:"id" validate:"required"`
+		Page    int    `form:"page,default=1"`
+		Tenant  string `header:"X-Tenant" validate:"required"`
+		Session string `cookie:"sid"`
+	}
+	e := newTestEngine()
+	e.Group("u").Get("/:id/orders", func(c *Context) {
+		var req request
+		if err := c.ShouldBindAll(&req); err != nil {
+			_ = c.FailValidation(err)
+			return
+		}
+		_ = c.String(http.StatusOK, "%d %d %s %s", req.Id, req.Page, req.Tenant, req.Session)
+	})
+	w := serve(e, http.MethodGet, "/u/7/orders?page=2", nil, map[string]string{"X-Tenant": "acme", "Cookie": "sid=abc"})
+	if w.Code != http.StatusOK || w.Body.String() != "7 2 acme abc" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+	// 所有来源绑定完后统一校验
+	w = serve(e, http.MethodGet, "/u/7/orders", nil, nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
+
+// 并发请求匹配同一棵路由树 每个请求拿到自己的路径参数
+func TestRouterParamsConcurrent(t *testing.T) {
+	e := newTestEngine()
+	g := e.Group("u")
+	g.Get("/:id", func(c *Context) {
+		_ = c.String(http.StatusOK, c.Param("id"))
+	})
+	g.Get("/:id/orders/:order", func(c *Context) {
+		_ = c.String(http.StatusOK, c.Param("id")+"/"+c.Param("order"))
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			target, want := "/u/a", "a"
+			if i%2 == 0 {
+				target, want = "/u/b/orders/c", "b/c"
+			}
+			if w := serve(e, http.MethodGet, target, nil, nil); w.Body.String() != want {
+				t.Errorf("%s: body = %q, want %q", target, w.Body.String(), want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestShouldBindContentType(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name" form:"name"`
+	}
+	var bindErr error
+	e := newTestEngine()
+	e.Group("b").Any("/x", func(c *Context) {
+		var u user
+		if bindErr = c.Bind(&u); bindErr != nil {
+			return
+		}
+		_ = c.String(http.StatusOK, u.Name)
+	})
+	tests := []struct {
+		method, target, contentType, body string
+	}{
+		{http.MethodGet, "/b/x?name=bob", "application/json", `{"name":"alice"}`},
+		{http.MethodHead, "/b/x?name=bob", "", ""},
+		// 没有 Content-Type 时当作表单 只有url参数
+		{http.MethodPost, "/b/x?name=bob", "", ""},
+		{http.MethodPost, "/b/x", "application/x-www-form-urlencoded", "name=bob"},
+		{http.MethodPost, "/b/x", "application/vnd.api+json", `{"name":"bob"}`},
+		{http.MethodPost, "/b/x", "application/atom+xml", `<user><name>bob</name></user>`},
+	}
+	for _, tt := range tests {
+		w := serve(e, tt.method, tt.target, strings.NewReader(tt.body), map[string]string{"Content-Type": tt.contentType})
+		if bindErr != nil || w.Code != http.StatusOK || (tt.method != http.MethodHead && w.Body.String() != "bob") {
+			t.Errorf("%s %q: status = %d body = %q err = %v", tt.method, tt.contentType, w.Code, w.Body.String(), bindErr)
+		}
+	}
+
+	w := serve(e, http.MethodPost, "/b/x", strings.NewReader("a,b"), map[string]string{"Content-Type": "text/csv"})
+	var be *BindError
+	if !errors.As(bindErr, &be) || be.Status != http.StatusUnsupportedMediaType || !errors.Is(bindErr, binding.ErrUnsupportedMediaType) {
+		t.Fatalf("err = %#v", bindErr)
+	}
+	if w.Code != http.StatusUnsupportedMediaType || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+		t.Fatalf("status = %d Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestProtoBufEncodeError(t *testing.T) {
+	e := newTestEngine()
+	var renderErr error
+	e.Group("p").Get("/x", func(c *Context) {
+		renderErr = c.ProtoBuf(http.StatusOK, map[string]string{"name": "bob"})
+	})
+	w := serve(e, http.MethodGet, "/p/x", nil, nil)
+	var encodeErr *render.EncodeError
+	if !errors.As(renderErr, &encodeErr) {
+		t.Fatalf("err = %v, want *render.EncodeError", renderErr)
+	}
+	// 只有 500 的提示 没有编码了一半的内容
+	if w.Code != http.StatusInternalServerError || w.Body.String() != http.StatusText(http.StatusInternalServerError)+"\n" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct == "application/x-protobuf" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+}
+
+func TestJSONP(t *testing.T) {
+	e := newTestEngine()
+	var renderErr error
+	e.Group("j").Get("/x", func(c *Context) {
+		renderErr = c.JSONP(http.StatusOK, map[string]int{"a": 1})
+	})
+	w := serve(e, http.MethodGet, "/j/x?callback=cb", nil, nil)
+	if renderErr != nil || w.Body.String() != `/**/cb({"a":1});` || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("body = %q err = %v", w.Body.String(), renderErr)
+	}
+	w = serve(e, http.MethodGet, "/j/x?callback=alert(document.cookie)", nil, nil)
+	if !errors.Is(renderErr, render.ErrInvalidCallback) || w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "alert") {
+		t.Fatalf("status = %d body = %q err = %v", w.Code, w.Body.String(), renderErr)
+	}
+}
+
+func TestJSONEncodeError(t *testing.T) {
+	data := map[string]any{"ch": make(chan int)}
+	renders := map[string]func(c *Context) error{
+		"JSON":         func(c *Context) error { return c.JSON(http.StatusOK, data) },
+		"IndentedJSON": func(c *Context) error { return c.IndentedJSON(http.StatusOK, data) },
+		"SecureJSON":   func(c *Context) error { return c.SecureJSON(http.StatusOK, data) },
+		"AsciiJSON":    func(c *Context) error { return c.AsciiJSON(http.StatusOK, data) },
+		"PureJSON":     func(c *Context) error { return c.PureJSON(http.StatusOK, data) },
+		"JSONP":        func(c *Context) error { return c.JSONP(http.StatusOK, data) },
+	}
+	for name, fn := range renders {
+		e := newTestEngine()
+		var renderErr error
+		e.Group("j").Get("/x", func(c *Context) {
+			renderErr = fn(c)
+		})
+		w := serve(e, http.MethodGet, "/j/x?callback=cb", nil, nil)
+		var encodeErr *render.EncodeError
+		if !errors.As(renderErr, &encodeErr) {
+			t.Errorf("%s: err = %v", name, renderErr)
+		}
+		if w.Code != http.StatusInternalServerError || w.Body.String() != http.StatusText(http.StatusInternalServerError)+"\n" {
+			t.Errorf("%s: status = %d body = %q", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestWebSocketBadHandshake(t *testing.T) {
+	// New 没有设置 Logger 握手失败时不能 panic
+	e := newTestEngine()
+	called := false
+	e.Group("ws").WebSocket("/x", func(c *Context) { called = true })
+	w := serve(e, http.MethodGet, "/ws/x", nil, nil)
+	if w.Code != http.StatusBadRequest || called {
+		t.Fatalf("status = %d called = %v", w.Code, called)
+	}
+}
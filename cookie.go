@@ -0,0 +1,184 @@
+package spxgo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCookieSecret = errors.New("cookie secrets not set")
+	ErrInvalidCookie  = errors.New("invalid cookie value")
+	ErrCookieExpired  = errors.New("cookie expired")
+)
+
+// Cookie 读取 SetCookie 写入的值 会还原 url.QueryEscape 的转义
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.R.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// deriveKey 从密钥派生出签名和加密各自使用的密钥 避免同一个密钥用于两种用途
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// cookieSecrets 空的密钥和没有设置一样 不能用来签名
+func (c *Context) cookieSecrets() ([][]byte, error) {
+	if c.engine == nil || len(c.engine.CookieSecrets) == 0 {
+		return nil, ErrNoCookieSecret
+	}
+	for _, secret := range c.engine.CookieSecrets {
+		if len(secret) == 0 {
+			return nil, ErrNoCookieSecret
+		}
+	}
+	return c.engine.CookieSecrets, nil
+}
+
+// cookiePayload 把过期时间放到值里 服务端也能校验 maxAge 而不是只依赖浏览器
+func cookiePayload(value string, maxAge int) string {
+	var expires int64
+	if maxAge > 0 {
+		expires = time.Now().Add(time.Duration(maxAge) * time.Second).Unix()
+	}
+	return strconv.FormatInt(expires, 10) + "|" + value
+}
+
+func parseCookiePayload(payload string) (string, error) {
+	ts, value, ok := strings.Cut(payload, "|")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	expires, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	if expires > 0 && time.Now().Unix() > expires {
+		return "", ErrCookieExpired
+	}
+	return value, nil
+}
+
+func signCookie(secret []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, deriveKey(secret, "spxgo-cookie-sign"))
+	// 名字参与签名 防止把一个cookie的值挪到另一个cookie上
+	mac.Write([]byte(name + "|" + payload))
+	return mac.Sum(nil)
+}
+
+// SetSignedCookie 写入带 HMAC 签名的 cookie 值对客户端可见但不能被篡改
+// 使用 Engine.CookieSecrets 中的第一个密钥签名
+func (c *Context) SetSignedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	secrets, err := c.cookieSecrets()
+	if err != nil {
+		return err
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(cookiePayload(value, maxAge)))
+	sig := base64.RawURLEncoding.EncodeToString(signCookie(secrets[0], name, payload))
+	c.SetCookie(name, payload+"."+sig, maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// SignedCookie 读取并校验签名 依次尝试所有密钥以支持密钥轮换
+func (c *Context) SignedCookie(name string) (string, error) {
+	secrets, err := c.cookieSecrets()
+	if err != nil {
+		return "", err
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	payload, sig, ok := strings.Cut(raw, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, secret := range secrets {
+		if hmac.Equal(mac, signCookie(secret, name, payload)) {
+			data, err := base64.RawURLEncoding.DecodeString(payload)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return parseCookiePayload(string(data))
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+func cookieAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(secret, "spxgo-cookie-encrypt"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetEncryptedCookie 写入 AES-GCM 加密的 cookie 客户端既看不到也不能篡改
+func (c *Context) SetEncryptedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	secrets, err := c.cookieSecrets()
+	if err != nil {
+		return err
+	}
+	aead, err := cookieAEAD(secrets[0])
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	// 名字作为附加数据 同样防止cookie之间互换
+	sealed := aead.Seal(nonce, nonce, []byte(cookiePayload(value, maxAge)), []byte(name))
+	c.SetCookie(name, base64.RawURLEncoding.EncodeToString(sealed), maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// EncryptedCookie 读取并解密 依次尝试所有密钥以支持密钥轮换
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	secrets, err := c.cookieSecrets()
+	if err != nil {
+		return "", err
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, secret := range secrets {
+		aead, err := cookieAEAD(secret)
+		if err != nil {
+			return "", err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return "", ErrInvalidCookie
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+		if err == nil {
+			return parseCookiePayload(string(plain))
+		}
+	}
+	return "", ErrInvalidCookie
+}
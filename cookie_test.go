@@ -0,0 +1,179 @@
+package spxgo
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCookie 用 secrets 写入一个cookie 返回编码后的值
+func writeCookie(t *testing.T, secrets [][]byte, set func(c *Context) error) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c := newTestContext(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.engine.CookieSecrets = secrets
+	if err := set(c); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v", cookies)
+	}
+	return cookies[0].Value
+}
+
+// readCookie 带着 name=value 的请求 用 secrets 读取
+func readCookie(secrets [][]byte, name, value string, get func(c *Context, name string) (string, error)) (string, error) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: name, Value: value})
+	c := newTestContext(httptest.NewRecorder(), r)
+	c.engine.CookieSecrets = secrets
+	return get(c, name)
+}
+
+// flip 修改 base64 编码中间的一个字符
+func flip(s string, i int) string {
+	b := []byte(s)
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+var (
+	oldSecret = []byte("old-secret-old-secret-old-secret")
+	newSecret = []byte("new-secret-new-secret-new-secret")
+)
+
+func TestSignedCookie(t *testing.T) {
+	secrets := [][]byte{newSecret}
+	value := writeCookie(t, secrets, func(c *Context) error {
+		return c.SetSignedCookie("user", "bob", 60, "", "", false, true)
+	})
+	get := (*Context).SignedCookie
+	if got, err := readCookie(secrets, "user", value, get); err != nil || got != "bob" {
+		t.Fatalf("SignedCookie = %q, %v", got, err)
+	}
+
+	payload, sig, _ := strings.Cut(value, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(cookiePayload("admin", 60)))
+	tests := []struct {
+		name, cookie, value string
+	}{
+		{"tampered value", "user", forged + "." + sig},
+		{"tampered mac", "user", payload + "." + flip(sig, len(sig)/2)},
+		{"no mac", "user", payload},
+		{"other name", "admin", value},
+	}
+	for _, tt := range tests {
+		if got, err := readCookie(secrets, tt.cookie, tt.value, get); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("%s: SignedCookie = %q, %v", tt.name, got, err)
+		}
+	}
+
+	// 服务端校验值里的过期时间
+	expired := base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + "|bob"))
+	expired += "." + base64.RawURLEncoding.EncodeToString(signCookie(newSecret, "user", expired))
+	if _, err := readCookie(secrets, "user", expired, get); !errors.Is(err, ErrCookieExpired) {
+		t.Fatalf("expired: err = %v", err)
+	}
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	secrets := [][]byte{newSecret}
+	value := writeCookie(t, secrets, func(c *Context) error {
+		return c.SetEncryptedCookie("user", "bob", 60, "", "", false, true)
+	})
+	if strings.Contains(value, "bob") {
+		t.Fatalf("value is not encrypted: %q", value)
+	}
+	get := (*Context).EncryptedCookie
+	if got, err := readCookie(secrets, "user", value, get); err != nil || got != "bob" {
+		t.Fatalf("EncryptedCookie = %q, %v", got, err)
+	}
+
+	tests := []struct {
+		name, cookie, value string
+	}{
+		{"tampered ciphertext", "user", flip(value, 20)},
+		{"tampered tag", "user", flip(value, len(value)-2)},
+		{"truncated", "user", value[:8]},
+		{"other name", "admin", value},
+	}
+	for _, tt := range tests {
+		if got, err := readCookie(secrets, tt.cookie, tt.value, get); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("%s: EncryptedCookie = %q, %v", tt.name, got, err)
+		}
+	}
+
+	aead, err := cookieAEAD(newSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	plain := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + "|bob"
+	expired := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plain), []byte("user")))
+	if _, err := readCookie(secrets, "user", expired, get); !errors.Is(err, ErrCookieExpired) {
+		t.Fatalf("expired: err = %v", err)
+	}
+}
+
+func TestCookieSecretRotation(t *testing.T) {
+	rotated := [][]byte{newSecret, oldSecret}
+	for _, tt := range []struct {
+		name string
+		set  func(c *Context) error
+		get  func(c *Context, name string) (string, error)
+	}{
+		{"signed", func(c *Context) error {
+			return c.SetSignedCookie("user", "bob", 0, "", "", false, true)
+		}, (*Context).SignedCookie},
+		{"encrypted", func(c *Context) error {
+			return c.SetEncryptedCookie("user", "bob", 0, "", "", false, true)
+		}, (*Context).EncryptedCookie},
+	} {
+		// 旧密钥写入的cookie在轮换后仍然有效
+		old := writeCookie(t, [][]byte{oldSecret}, tt.set)
+		if got, err := readCookie(rotated, "user", old, tt.get); err != nil || got != "bob" {
+			t.Fatalf("%s: old cookie = %q, %v", tt.name, got, err)
+		}
+		// 新写入的cookie使用第一个密钥 只有新密钥也能读取
+		value := writeCookie(t, rotated, tt.set)
+		if got, err := readCookie([][]byte{newSecret}, "user", value, tt.get); err != nil || got != "bob" {
+			t.Fatalf("%s: new cookie = %q, %v", tt.name, got, err)
+		}
+		if _, err := readCookie([][]byte{oldSecret}, "user", value, tt.get); !errors.Is(err, ErrInvalidCookie) {
+			t.Fatalf("%s: new cookie verified with old secret: %v", tt.name, err)
+		}
+	}
+}
+
+func TestCookieNoSecret(t *testing.T) {
+	for _, secrets := range [][][]byte{nil, {}, {nil}, {newSecret, {}}} {
+		w := httptest.NewRecorder()
+		c := newTestContext(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		c.engine.CookieSecrets = secrets
+		if err := c.SetSignedCookie("user", "bob", 0, "", "", false, true); !errors.Is(err, ErrNoCookieSecret) {
+			t.Errorf("%v: SetSignedCookie err = %v", secrets, err)
+		}
+		if err := c.SetEncryptedCookie("user", "bob", 0, "", "", false, true); !errors.Is(err, ErrNoCookieSecret) {
+			t.Errorf("%v: SetEncryptedCookie err = %v", secrets, err)
+		}
+		if len(w.Result().Cookies()) != 0 {
+			t.Errorf("%v: cookie was written", secrets)
+		}
+		get := []func(c *Context, name string) (string, error){(*Context).SignedCookie, (*Context).EncryptedCookie}
+		for _, g := range get {
+			if _, err := readCookie(secrets, "user", "x", g); !errors.Is(err, ErrNoCookieSecret) {
+				t.Errorf("%v: read err = %v", secrets, err)
+			}
+		}
+	}
+}
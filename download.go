@@ -0,0 +1,339 @@
+package spxgo
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errNoOverlap = errors.New("invalid range: failed to overlap")
+
+// maxRanges 一个请求最多的分段数 超出时忽略 Range 返回整个内容
+const maxRanges = 16
+
+// httpRange 请求中的一段 Range
+type httpRange struct {
+	start, length int64
+}
+
+func (r httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRange 解析 Range: bytes=0-99,200-,-50
+func parseRange(s string, size int64) ([]httpRange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, errors.New("invalid range")
+	}
+	var ranges []httpRange
+	noOverlap := false
+	for _, ra := range strings.Split(s[len(b):], ",") {
+		ra = textproto.TrimString(ra)
+		if ra == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(ra, "-")
+		if !ok {
+			return nil, errors.New("invalid range")
+		}
+		start, end = textproto.TrimString(start), textproto.TrimString(end)
+		var r httpRange
+		if start == "" {
+			// -N 表示最后 N 个字节
+			if end == "" || end[0] == '-' {
+				return nil, errors.New("invalid range")
+			}
+			i, err := strconv.ParseInt(end, 10, 64)
+			if i < 0 || err != nil {
+				return nil, errors.New("invalid range")
+			}
+			if i > size {
+				i = size
+			}
+			r.start = size - i
+			r.length = size - r.start
+		} else {
+			i, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || i < 0 {
+				return nil, errors.New("invalid range")
+			}
+			if i >= size {
+				noOverlap = true
+				continue
+			}
+			r.start = i
+			if end == "" {
+				r.length = size - r.start
+			} else {
+				i, err := strconv.ParseInt(end, 10, 64)
+				if err != nil || r.start > i {
+					return nil, errors.New("invalid range")
+				}
+				if i >= size {
+					i = size - 1
+				}
+				r.length = i - r.start + 1
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	if noOverlap && len(ranges) == 0 {
+		return nil, errNoOverlap
+	}
+	return ranges, nil
+}
+
+// sumRangesSize 各分段长度之和
+func sumRangesSize(ranges []httpRange) (size int64) {
+	for _, ra := range ranges {
+		size += ra.length
+	}
+	return
+}
+
+// mergeRanges 按起点排序后合并重叠和相邻的分段
+func mergeRanges(ranges []httpRange) []httpRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	merged := ranges[:1]
+	for _, ra := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if ra.start <= last.start+last.length {
+			if end := ra.start + ra.length; end > last.start+last.length {
+				last.length = end - last.start
+			}
+			continue
+		}
+		merged = append(merged, ra)
+	}
+	return merged
+}
+
+// etagMatch If-None-Match 使用弱比较
+func etagMatch(header, etag string) bool {
+	if header == "" || etag == "" {
+		return false
+	}
+	trim := func(s string) string {
+		return strings.TrimPrefix(strings.TrimSpace(s), "W/")
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || trim(v) == trim(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfRange If-Range 为 ETag 时要求强比较 为时间时要求和 Last-Modified 完全相同
+// 不满足时忽略 Range 返回整个内容
+func checkIfRange(r *http.Request, etag, lastModified string) bool {
+	ir := r.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		if strings.HasPrefix(ir, "W/") || strings.HasPrefix(etag, "W/") {
+			return false
+		}
+		return ir == etag
+	}
+	if lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ir)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && modified.Equal(since)
+}
+
+// ChecksumHeaders 读取整个内容计算 ETag Digest 和 Content-MD5 然后把 reader 恢复到开头
+// 结果可以作为 DataFromReader 的 headers 内容较大时最好缓存起来 不要每次请求都计算
+func ChecksumHeaders(reader io.ReadSeeker) (map[string]string, error) {
+	h256, hMD5 := sha256.New(), md5.New()
+	if _, err := io.Copy(io.MultiWriter(h256, hMD5), reader); err != nil {
+		return nil, err
+	}
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sum256 := h256.Sum(nil)
+	return map[string]string{
+		"ETag":        `"` + hex.EncodeToString(sum256) + `"`,
+		"Digest":      "sha-256=" + base64.StdEncoding.EncodeToString(sum256),
+		"Content-MD5": base64.StdEncoding.EncodeToString(hMD5.Sum(nil)),
+	}, nil
+}
+
+// DataFromReader 用于运行时生成的内容的下载
+// 支持 Range/If-Range、多段 multipart/byteranges 以及 If-None-Match/If-Modified-Since
+// ETag Last-Modified Digest 等通过 headers 传入 不会读取整个内容去计算 需要时使用 ChecksumHeaders
+// size 小于 0 时通过 Seek 获取内容长度
+func (c *Context) DataFromReader(status int, size int64, contentType string, reader io.ReadSeeker, headers map[string]string) error {
+	var err error
+	if size < 0 {
+		if size, err = reader.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+		if _, err = reader.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	header := c.W.Header()
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	// 非 200 的内容不参与协商和分段
+	if status != http.StatusOK {
+		return c.writeReader(status, size, reader)
+	}
+	header.Set("Accept-Ranges", "bytes")
+	if notModified(c.R, etag, lastModified) {
+		header.Del("Content-Type")
+		header.Del("Content-MD5")
+		c.W.WriteHeader(http.StatusNotModified)
+		c.StatusCode = http.StatusNotModified
+		return nil
+	}
+	rangeHeader := c.R.Header.Get("Range")
+	if rangeHeader == "" || !checkIfRange(c.R, etag, lastModified) {
+		return c.writeReader(status, size, reader)
+	}
+	ranges, err := parseRange(rangeHeader, size)
+	if err != nil {
+		if errors.Is(err, errNoOverlap) {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		}
+		header.Del("Content-MD5")
+		header.Del("Digest")
+		c.StatusCode = http.StatusRequestedRangeNotSatisfiable
+		http.Error(c.W, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	}
+	// 分段过多或者总长度超过内容本身(比如 0-,0-,0-)时 分段响应会放大流量 直接返回整个内容
+	if len(ranges) == 0 || len(ranges) > maxRanges || sumRangesSize(ranges) > size {
+		return c.writeReader(status, size, reader)
+	}
+	ranges = mergeRanges(ranges)
+	// Content-MD5 描述的是整个内容 分段响应时去掉
+	header.Del("Content-MD5")
+	if len(ranges) == 1 {
+		ra := ranges[0]
+		if _, err := reader.Seek(ra.start, io.SeekStart); err != nil {
+			return err
+		}
+		header.Set("Content-Range", ra.contentRange(size))
+		return c.writeReader(http.StatusPartialContent, ra.length, reader)
+	}
+	return c.writeMultiRange(size, contentType, ranges, reader)
+}
+
+func (c *Context) writeReader(status int, size int64, reader io.Reader) error {
+	c.W.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	c.W.WriteHeader(status)
+	c.StatusCode = status
+	if c.R.Method == http.MethodHead {
+		return nil
+	}
+	_, err := io.CopyN(c.W, reader, size)
+	return err
+}
+
+// writeMultiRange 多段 Range 使用 multipart/byteranges 返回
+func (c *Context) writeMultiRange(size int64, contentType string, ranges []httpRange, reader io.ReadSeeker) error {
+	// 先用一个计数器算出总长度 再真正写入
+	counter := &countingWriter{}
+	mw := multipart.NewWriter(counter)
+	for _, ra := range ranges {
+		if _, err := mw.CreatePart(rangeMIMEHeader(ra, contentType, size)); err != nil {
+			return err
+		}
+		counter.n += ra.length
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	header := c.W.Header()
+	header.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	header.Set("Content-Length", strconv.FormatInt(counter.n, 10))
+	c.W.WriteHeader(http.StatusPartialContent)
+	c.StatusCode = http.StatusPartialContent
+	if c.R.Method == http.MethodHead {
+		return nil
+	}
+	boundary := mw.Boundary()
+	mw = multipart.NewWriter(c.W)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, ra := range ranges {
+		part, err := mw.CreatePart(rangeMIMEHeader(ra, contentType, size))
+		if err != nil {
+			return err
+		}
+		if _, err := reader.Seek(ra.start, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(part, reader, ra.length); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func rangeMIMEHeader(ra httpRange, contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {ra.contentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// Stream 持续调用 step 写入数据并刷新，step 返回 false 时结束
+// 客户端断开时返回 true，调用方可以据此停止后续的处理
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.W)
+			c.flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
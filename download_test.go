@@ -0,0 +1,168 @@
+package spxgo
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const downloadContent = "0123456789abcdefghij" // 20 个字节
+
+func serveData(t *testing.T, reqHeader map[string]string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/file", nil)
+	for k, v := range reqHeader {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	c := newTestContext(w, r)
+	if err := c.DataFromReader(http.StatusOK, -1, "text/plain", strings.NewReader(downloadContent), headers); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestDataFromReaderRange(t *testing.T) {
+	cases := []struct {
+		rangeHeader  string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"", http.StatusOK, downloadContent, ""},
+		{"bytes=0-4", http.StatusPartialContent, "01234", "bytes 0-4/20"},
+		{"bytes=15-", http.StatusPartialContent, "fghij", "bytes 15-19/20"},
+		{"bytes=-3", http.StatusPartialContent, "hij", "bytes 17-19/20"},
+		{"bytes=10-100", http.StatusPartialContent, "abcdefghij", "bytes 10-19/20"},
+		// 重叠和相邻的分段合并为一段
+		{"bytes=0-4,3-7,8-9", http.StatusPartialContent, "0123456789", "bytes 0-9/20"},
+		// 总长度超过内容本身 返回整个内容
+		{"bytes=0-,0-,0-", http.StatusOK, downloadContent, ""},
+		{"bytes=50-", http.StatusRequestedRangeNotSatisfiable, "", "bytes */20"},
+	}
+	for _, tc := range cases {
+		w := serveData(t, map[string]string{"Range": tc.rangeHeader}, nil)
+		if w.Code != tc.status {
+			t.Errorf("Range %q: status = %d, want %d", tc.rangeHeader, w.Code, tc.status)
+			continue
+		}
+		if got := w.Header().Get("Content-Range"); got != tc.contentRange {
+			t.Errorf("Range %q: Content-Range = %q, want %q", tc.rangeHeader, got, tc.contentRange)
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("Range %q: body = %q, want %q", tc.rangeHeader, w.Body.String(), tc.body)
+		}
+	}
+}
+
+func TestDataFromReaderTooManyRanges(t *testing.T) {
+	var ranges []string
+	for i := 0; i < maxRanges+1; i++ {
+		ranges = append(ranges, "0-0")
+	}
+	w := serveData(t, map[string]string{"Range": "bytes=" + strings.Join(ranges, ",")}, nil)
+	if w.Code != http.StatusOK || w.Body.String() != downloadContent {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestDataFromReaderMultiRange(t *testing.T) {
+	w := serveData(t, map[string]string{"Range": "bytes=10-11,0-1"}, nil)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d", w.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+		t.Fatalf("Content-Length = %s, body length = %d", w.Header().Get("Content-Length"), w.Body.Len())
+	}
+	reader := multipart.NewReader(bytes.NewReader(w.Body.Bytes()), params["boundary"])
+	// 分段按照起点排序
+	want := []struct{ contentRange, body string }{
+		{"bytes 0-1/20", "01"},
+		{"bytes 10-11/20", "ab"},
+	}
+	for _, p := range want {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Range") != p.contentRange || string(body) != p.body {
+			t.Fatalf("part: Content-Range = %q body = %q, want %q %q", part.Header.Get("Content-Range"), body, p.contentRange, p.body)
+		}
+	}
+	if _, err = reader.NextPart(); err != io.EOF {
+		t.Fatalf("expected 2 parts, err = %v", err)
+	}
+}
+
+func TestDataFromReaderIfRange(t *testing.T) {
+	modified := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat)
+	headers := map[string]string{"ETag": `"v1"`, "Last-Modified": modified}
+	cases := []struct {
+		ifRange string
+		status  int
+	}{
+		{`"v1"`, http.StatusPartialContent},
+		{`"v2"`, http.StatusOK},
+		{`W/"v1"`, http.StatusOK}, // If-Range 要求强比较
+		{modified, http.StatusPartialContent},
+		{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat), http.StatusOK},
+	}
+	for _, tc := range cases {
+		w := serveData(t, map[string]string{"Range": "bytes=0-4", "If-Range": tc.ifRange}, headers)
+		if w.Code != tc.status {
+			t.Errorf("If-Range %q: status = %d, want %d", tc.ifRange, w.Code, tc.status)
+		}
+	}
+}
+
+func TestDataFromReaderNotModified(t *testing.T) {
+	modified := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	headers := map[string]string{"ETag": `"v1"`, "Last-Modified": modified.Format(http.TimeFormat)}
+	cases := []struct {
+		reqHeader map[string]string
+		status    int
+	}{
+		{map[string]string{"If-None-Match": `"v1"`}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"v2"`}, http.StatusOK},
+		{map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}, http.StatusNotModified},
+		{map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		// 有 If-None-Match 时忽略 If-Modified-Since
+		{map[string]string{"If-None-Match": `"v2"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if w := serveData(t, tc.reqHeader, headers); w.Code != tc.status {
+			t.Errorf("%v: status = %d, want %d", tc.reqHeader, w.Code, tc.status)
+		}
+	}
+}
+
+func TestChecksumHeaders(t *testing.T) {
+	reader := strings.NewReader(downloadContent)
+	headers, err := ChecksumHeaders(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers["ETag"] == "" || !strings.HasPrefix(headers["Digest"], "sha-256=") || headers["Content-MD5"] == "" {
+		t.Fatalf("unexpected headers: %v", headers)
+	}
+	// reader 恢复到开头
+	if rest, _ := io.ReadAll(reader); string(rest) != downloadContent {
+		t.Fatalf("reader was not rewound: %q", rest)
+	}
+	// 不传入时不计算
+	if w := serveData(t, nil, nil); w.Header().Get("ETag") != "" || w.Header().Get("Digest") != "" {
+		t.Fatalf("checksum computed without opting in: %v", w.Header())
+	}
+}
@@ -0,0 +1,220 @@
+package spxgo
+
+import (
+	"errors"
+	"gitbuh.com/spxzx/spxgo/render"
+	"gitbuh.com/spxzx/spxgo/serror"
+	ut "github.com/go-playground/universal-translator"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandlerFuncE 可以返回错误的处理函数 用 HandleE 转换后注册路由
+// 返回的错误交给 Engine.RegisterErrorHandler 注册的处理函数生成响应
+type HandlerFuncE func(c *Context) error
+
+// HandleE 把 HandlerFuncE 转换为 HandlerFunc 返回的错误等同于调用了 Context.Error
+func HandleE(handlerFunc HandlerFuncE) HandlerFunc {
+	return func(c *Context) {
+		_ = c.Error(handlerFunc(c))
+	}
+}
+
+// Errors Context.Error 收集的多个错误 errors.Is 和 errors.As 会依次检查其中的每一个
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Errors) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Error 记录一个错误 处理函数返回后交给 ErrorHandler 生成响应 err 为空时忽略
+// 返回 err 本身 可以写成 return c.Error(err) Bind 系列方法返回的 *BindError 已经记录过 不会重复记录
+func (c *Context) Error(err error) error {
+	if err != nil && !c.recorded(err) {
+		c.errs = append(c.errs, err)
+	}
+	return err
+}
+
+// recorded err 是已经记录过的 *BindError 比如 Bind 失败后处理函数又把它返回
+func (c *Context) recorded(err error) bool {
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		return false
+	}
+	for _, e := range c.errs {
+		if e == error(bindErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors 当前请求通过 Context.Error 记录的错误
+func (c *Context) Errors() Errors {
+	return c.errs
+}
+
+// handleErrors 处理还没有处理过的错误 一个错误时直接传给 ErrorHandler 多个时传入 Errors
+// 已经写入了响应(见 Context.Written)时只记录日志
+func (c *Context) handleErrors() {
+	if c.errsHandled >= len(c.errs) {
+		return
+	}
+	errs := c.errs[c.errsHandled:]
+	c.errsHandled = len(c.errs)
+	var err error = errs
+	if len(errs) == 1 {
+		err = errs[0]
+	}
+	if c.Written() {
+		c.logError(err)
+		return
+	}
+	handler := c.engine.errorHandler
+	if handler == nil {
+		// ErrorHandler 拿不到 Context 默认的处理函数在这里带上请求的语言
+		trans := c.Translator()
+		handler = func(err error) (int, any) {
+			return defaultErrorHandler(err, trans)
+		}
+	}
+	status, body := handler(err)
+	var renderErr error
+	if problem, ok := body.(*serror.Problem); ok {
+		// 没有设置状态码的 Problem 使用 ErrorHandler 返回的状态码
+		switch {
+		case problem == nil:
+			problem = serror.NewProblem(status, "")
+		case problem.Status == 0:
+			p := *problem
+			p.Status = status
+			problem = &p
+		}
+		renderErr = c.Problem(problem)
+	} else {
+		renderErr = c.JSON(status, body)
+	}
+	if renderErr != nil {
+		c.logError(renderErr)
+	}
+}
+
+// ErrorCheck 当前请求的错误检查 Put(err) 在 err 不为空时中断处理函数 由 Recovery 生成响应
+// 没有通过 Result 设置处理方法时交给 ErrorHandler 默认返回 SpxError 的状态码 未设置时为500
+// 需要 Recovery 中间件
+func (c *Context) ErrorCheck() *serror.SpxError {
+	if c.errorCheck == nil {
+		c.errorCheck = serror.Default()
+	}
+	return c.errorCheck
+}
+
+func (c *Context) logError(err error) {
+	if c.Logger != nil {
+		c.Logger.Error(err)
+	}
+}
+
+// defaultErrorHandler 返回 serror.Problem 错误本身是 *serror.Problem 时直接使用
+// *serror.SpxError 使用它的状态码 4xx 时错误内容作为 detail 业务错误码放在扩展字段 code 中
+// 校验错误返回422 字段错误按照 trans 的语言放在扩展字段 errors 中 请求体过大返回413
+// *BindError 使用它的状态码 错误内容作为 detail 其它错误返回500且不暴露错误内容
+func defaultErrorHandler(err error, trans ut.Translator) (int, any) {
+	var problem *serror.Problem
+	if errors.As(err, &problem) && problem != nil {
+		return problem.Status, problem
+	}
+	var spxError *serror.SpxError
+	if errors.As(err, &spxError) {
+		status := spxError.StatusCode()
+		detail := ""
+		if status < http.StatusInternalServerError {
+			detail = spxError.Error()
+		}
+		problem = serror.NewProblem(status, detail)
+		if spxError.Code != 0 {
+			problem.With("code", spxError.Code)
+		}
+		return status, problem
+	}
+	if problem, ok := validationProblem(err, trans); ok {
+		return problem.Status, problem
+	}
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return bindErr.Status, serror.NewProblem(bindErr.Status, bindErr.Err.Error())
+	}
+	return http.StatusInternalServerError, serror.NewProblem(http.StatusInternalServerError, "")
+}
+
+// Problem 按照 Accept 输出 application/problem+json 或 application/problem+xml
+// 状态码使用 problem.Status 为0时使用500 Instance 为空时填入请求路径 p 为空时返回500
+// 补全的字段写在副本上 problem 可以是包级别的变量
+func (c *Context) Problem(p *serror.Problem) error {
+	var problem serror.Problem
+	if p != nil {
+		problem = *p
+	}
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" && (problem.Type == "" || problem.Type == "about:blank") {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = c.R.URL.Path
+	}
+	if prefersXML(c.R.Header.Get("Accept")) {
+		return c.Render(problem.Status, &render.ProblemXML{Data: &problem})
+	}
+	return c.Render(problem.Status, &render.ProblemJSON{Data: &problem})
+}
+
+// prefersXML Accept 中xml类型的权重高于json类型时返回 true 其它情况(包括 */*)都使用json
+func prefersXML(accept string) bool {
+	var xmlQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if f, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case serror.MIMEProblemXML, "application/xml", "text/xml":
+			if q > xmlQ {
+				xmlQ = q
+			}
+		case serror.MIMEProblemJSON, "application/json", "application/*", "*/*":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		}
+	}
+	return xmlQ > jsonQ
+}
@@ -0,0 +1,202 @@
+package spxgo
+
+import (
+	"errors"
+	"gitbuh.com/spxzx/spxgo/serror"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var errTest = errors.New("test error")
+
+func TestHandleErrorsAfterDirectWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		middlewares []MiddlewareFunc
+		write       func(c *Context)
+	}{
+		{"write", nil, func(c *Context) {
+			_, _ = c.W.Write([]byte("partial"))
+		}},
+		{"flush", nil, func(c *Context) {
+			c.W.Header().Set("Content-Type", "text/event-stream")
+			c.W.(http.Flusher).Flush()
+			_, _ = c.W.Write([]byte("partial"))
+		}},
+		{"header", nil, func(c *Context) {
+			c.W.WriteHeader(http.StatusAccepted)
+			_, _ = c.W.Write([]byte("partial"))
+		}},
+		// 内容还缓存在 Compress 中
+		{"compress", []MiddlewareFunc{Compress(CompressConfig{})}, func(c *Context) {
+			_, _ = c.W.Write([]byte("partial"))
+		}},
+		{"etag", []MiddlewareFunc{ETag(ETagConfig{})}, func(c *Context) {
+			_, _ = c.W.Write([]byte("partial"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine()
+			e.Group("t").Any("/x", HandleE(func(c *Context) error {
+				tt.write(c)
+				return errTest
+			}), tt.middlewares...)
+			w := serve(e, http.MethodGet, "/t/x", nil, map[string]string{"Accept-Encoding": "gzip"})
+			// 已经写入了响应 错误只记录日志 不再追加 Problem
+			if w.Body.String() != "partial" {
+				t.Fatalf("body = %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleErrorsAfterPanic(t *testing.T) {
+	e := newTestEngine()
+	e.Group("t").Any("/x", func(c *Context) {
+		_, _ = c.W.Write([]byte("partial"))
+		panic("boom")
+	}, Recovery)
+	w := serve(e, http.MethodGet, "/t/x", nil, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestBindErrorResponse(t *testing.T) {
+	type user struct {
+		Name string `json:"name" validate:"required"`
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"syntax", "application/json", `{"name":`, http.StatusBadRequest},
+		{"validation", "application/json", `{}`, http.StatusUnprocessableEntity},
+		{"media type", "text/csv", `a,b`, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs Errors
+			returned, ignored := newTestEngine(), newTestEngine()
+			returned.Group("t").Any("/x", HandleE(func(c *Context) error {
+				var u user
+				if err := c.Bind(&u); err != nil {
+					return err
+				}
+				return c.String(http.StatusOK, "ok")
+			}), func(next HandlerFunc) HandlerFunc {
+				return func(c *Context) {
+					next(c)
+					errs = c.Errors()
+				}
+			})
+			// 处理函数没有返回错误也会生成响应
+			ignored.Group("t").Any("/x", func(c *Context) {
+				var u user
+				_ = c.Bind(&u)
+			})
+			for _, e := range []*Engine{returned, ignored} {
+				w := serve(e, http.MethodPost, "/t/x", strings.NewReader(tt.body), map[string]string{"Content-Type": tt.contentType})
+				if w.Code != tt.status || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+					t.Fatalf("status = %d Content-Type = %q body = %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+				}
+			}
+			// 返回的 *BindError 不会重复记录
+			var bindErr *BindError
+			if len(errs) != 1 || !errors.As(errs[0], &bindErr) || bindErr.Status != tt.status {
+				t.Fatalf("errors = %v", errs)
+			}
+		})
+	}
+}
+
+func TestHandleErrorsProblemStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   any
+		want   int
+	}{
+		{"status from handler", http.StatusConflict, &serror.Problem{Title: "Conflict"}, http.StatusConflict},
+		{"problem status wins", http.StatusConflict, serror.NewProblem(http.StatusGone, ""), http.StatusGone},
+		{"nil problem", http.StatusTeapot, (*serror.Problem)(nil), http.StatusTeapot},
+		{"no status", 0, &serror.Problem{}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine()
+			e.Group("t").Any("/x", HandleE(func(c *Context) error {
+				return errTest
+			}))
+			e.RegisterErrorHandler(func(err error) (int, any) {
+				return tt.status, tt.body
+			})
+			w := serve(e, http.MethodGet, "/t/x", nil, nil)
+			if w.Code != tt.want || !strings.Contains(w.Body.String(), `"status":`+strconv.Itoa(tt.want)) {
+				t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+			}
+		})
+	}
+	// 作为错误返回的空 *Problem 交给默认的 ErrorHandler 也不会 panic
+	e := newTestEngine()
+	e.Group("t").Any("/x", HandleE(func(c *Context) error {
+		var problem *serror.Problem
+		return problem
+	}))
+	w := serve(e, http.MethodGet, "/t/x", nil, nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", w.Code)
+	}
+}
+
+func TestProblemNil(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newTestContext(w, httptest.NewRequest(http.MethodGet, "/p", nil))
+	if err := c.Problem(nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"instance":"/p"`) {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestErrorCheck(t *testing.T) {
+	e := newTestEngine()
+	g := e.Group("t")
+	g.Use(Recovery)
+	g.Get("/status", func(c *Context) {
+		c.ErrorCheck().WithStatus(http.StatusNotFound).WithCode(1001).Put(errTest)
+		_ = c.String(http.StatusOK, "unreachable")
+	})
+	g.Get("/default", func(c *Context) {
+		c.ErrorCheck().Put(nil)
+		c.ErrorCheck().Put(errTest)
+	})
+	g.Get("/result", func(c *Context) {
+		check := c.ErrorCheck()
+		check.Result(func(err *serror.SpxError) {
+			_ = c.String(http.StatusTeapot, "result: %v", err)
+		})
+		check.Put(errTest)
+	})
+	w := serve(e, http.MethodGet, "/t/status", nil, nil)
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") ||
+		!strings.Contains(w.Body.String(), `"detail":"test error"`) || !strings.Contains(w.Body.String(), `"code":1001`) {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+	// 每个请求拿到新的实例 上一个请求设置的状态码不会保留 没有状态码时返回500且不暴露错误内容
+	w = serve(e, http.MethodGet, "/t/default", nil, nil)
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "test error") || strings.Contains(w.Body.String(), "code") {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+	w = serve(e, http.MethodGet, "/t/result", nil, nil)
+	if w.Code != http.StatusTeapot || w.Body.String() != "result: test error" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,267 @@
+package spxgo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultETagMaxSize = 4 << 20 // 4M
+
+type ETagConfig struct {
+	Weak    bool // 生成弱ETag 内容语义相同即可 比如压缩前后
+	MaxSize int  // 超过该字节数的响应不再缓冲 直接输出且不生成ETag 默认4M
+}
+
+// ETag 缓冲GET请求的200响应 计算ETag 处理 If-None-Match 和 If-Modified-Since 返回304
+// 处理函数已经通过 SetETag 设置了ETag时直接使用 流式响应(调用了Flush)不处理
+// HEAD请求不处理 没有响应体时算出的ETag和GET的不同 Content-Length 也会变成0
+func ETag(conf ETagConfig) MiddlewareFunc {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = defaultETagMaxSize
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.R.Method != http.MethodGet {
+				next(c)
+				return
+			}
+			w := &etagWriter{ResponseWriter: c.W, conf: &conf}
+			c.W = w
+			// 发生 panic 时缓冲的内容直接丢弃 Recovery 可以重新写入响应
+			defer func() {
+				c.W = w.ResponseWriter
+			}()
+			next(c)
+			if err := w.close(c.R); err != nil && c.Logger != nil {
+				c.Logger.Error(err)
+			}
+			if w.notModified {
+				c.StatusCode = http.StatusNotModified
+			}
+		}
+	}
+}
+
+type etagWriter struct {
+	http.ResponseWriter
+	conf        *ETagConfig
+	status      int
+	buf         bytes.Buffer
+	passthrough bool // 不再缓冲 直接写入
+	notModified bool
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status != http.StatusOK {
+		w.stopBuffering()
+	}
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.buf.Len()+len(p) > w.conf.MaxSize {
+		if err := w.stopBuffering(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// stopBuffering 写出响应头和已经缓冲的内容 之后直接写入
+func (w *etagWriter) stopBuffering() error {
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *etagWriter) Flush() {
+	if !w.passthrough {
+		if err := w.stopBuffering(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not supported")
+	}
+	w.passthrough = true
+	return hijacker.Hijack()
+}
+
+// Written 内容还缓存在这里时也算已经写入
+func (w *etagWriter) Written() bool {
+	return w.status != 0 || w.passthrough
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close 处理函数返回后 计算ETag并根据条件请求头决定返回304还是完整内容
+func (w *etagWriter) close(r *http.Request) error {
+	if w.passthrough {
+		return nil
+	}
+	if w.status == 0 {
+		// 没有写入任何内容
+		return nil
+	}
+	header := w.Header()
+	etag := header.Get("ETag")
+	if etag == "" {
+		etag = computeETag(w.buf.Bytes(), w.conf.Weak)
+		header.Set("ETag", etag)
+	}
+	if notModified(r, etag, header.Get("Last-Modified")) {
+		w.notModified = true
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	if header.Get("Content-Encoding") == "" {
+		header.Set("Content-Length", strconv.Itoa(w.buf.Len()))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
+
+func computeETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// notModified 有 If-None-Match 时只比较ETag(弱比较) 没有时才看 If-Modified-Since
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// CacheControl 响应头 Cache-Control 时间为0的指令不输出
+type CacheControl struct {
+	Public               bool
+	Private              bool
+	NoCache              bool // 可以缓存 但每次使用前要向服务端验证
+	NoStore              bool // 不允许缓存
+	NoTransform          bool // 代理不能修改内容 Compress 中间件也不会压缩
+	MustRevalidate       bool
+	Immutable            bool // 有效期内内容不会变化 浏览器刷新也不需要验证
+	MaxAge               time.Duration
+	SMaxAge              time.Duration // 共享缓存(CDN)的有效期
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+}
+
+func (cc CacheControl) String() string {
+	var directives []string
+	flag := func(on bool, name string) {
+		if on {
+			directives = append(directives, name)
+		}
+	}
+	seconds := func(d time.Duration, name string) {
+		if d > 0 {
+			directives = append(directives, name+"="+strconv.FormatInt(int64(d/time.Second), 10))
+		}
+	}
+	flag(cc.Public, "public")
+	flag(cc.Private, "private")
+	flag(cc.NoCache, "no-cache")
+	flag(cc.NoStore, "no-store")
+	flag(cc.NoTransform, "no-transform")
+	flag(cc.MustRevalidate, "must-revalidate")
+	flag(cc.Immutable, "immutable")
+	seconds(cc.MaxAge, "max-age")
+	seconds(cc.SMaxAge, "s-maxage")
+	seconds(cc.StaleWhileRevalidate, "stale-while-revalidate")
+	seconds(cc.StaleIfError, "stale-if-error")
+	return strings.Join(directives, ", ")
+}
+
+// SetCacheControl 设置 Cache-Control 需要在写入内容之前调用
+func (c *Context) SetCacheControl(cc CacheControl) {
+	c.W.Header().Set("Cache-Control", cc.String())
+}
+
+// CacheFor 允许浏览器和代理缓存 d 时间
+func (c *Context) CacheFor(d time.Duration) {
+	c.SetCacheControl(CacheControl{Public: true, MaxAge: d})
+}
+
+// NoCache 每次使用缓存前都要验证 配合 ETag 中间件可以返回304
+func (c *Context) NoCache() {
+	c.SetCacheControl(CacheControl{NoCache: true})
+}
+
+// NoStore 不允许任何缓存 用于包含敏感信息的响应
+func (c *Context) NoStore() {
+	c.SetCacheControl(CacheControl{NoStore: true})
+}
+
+// SetETag 设置ETag etag 不需要带引号 ETag 中间件会直接使用它而不再计算
+func (c *Context) SetETag(etag string, weak bool) {
+	etag = `"` + strings.Trim(etag, `"`) + `"`
+	if weak {
+		etag = "W/" + etag
+	}
+	c.W.Header().Set("ETag", etag)
+}
+
+// SetLastModified 设置 Last-Modified ETag 中间件用它处理 If-Modified-Since
+func (c *Context) SetLastModified(t time.Time) {
+	c.W.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
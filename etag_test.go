@@ -0,0 +1,121 @@
+package spxgo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETagIfNoneMatch(t *testing.T) {
+	e := newTestEngine()
+	e.Group("e").Any("/x", func(c *Context) {
+		_ = c.String(http.StatusOK, "hello")
+	}, ETag(ETagConfig{}))
+	w := serve(e, http.MethodGet, "/e/x", nil, nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "hello" || etag != computeETag([]byte("hello"), false) {
+		t.Fatalf("status=%d body=%q etag=%q", w.Code, w.Body.String(), etag)
+	}
+	if w.Header().Get("Content-Length") != "5" {
+		t.Fatalf("Content-Length = %q", w.Header().Get("Content-Length"))
+	}
+	// 弱比较 W/ 前缀和列表中的任意一个匹配即可
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = serve(e, http.MethodGet, "/e/x", nil, map[string]string{"If-None-Match": inm})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+			t.Fatalf("If-None-Match %q: status=%d body=%q", inm, w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Fatalf("304 ETag = %q", w.Header().Get("ETag"))
+		}
+	}
+	w = serve(e, http.MethodGet, "/e/x", nil, map[string]string{"If-None-Match": `"other"`})
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("status=%d body=%q", w.Code, w.Body.String())
+	}
+}
+
+func TestETagIfModifiedSince(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := newTestEngine()
+	e.Group("e").Any("/x", func(c *Context) {
+		c.SetLastModified(modified)
+		c.SetETag("v1", false)
+		_ = c.String(http.StatusOK, "hello")
+	}, ETag(ETagConfig{Weak: true}))
+	w := serve(e, http.MethodGet, "/e/x", nil, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)})
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("status = %d", w.Code)
+	}
+	// 处理函数设置的ETag直接使用
+	if w.Header().Get("ETag") != `"v1"` {
+		t.Fatalf("ETag = %q", w.Header().Get("ETag"))
+	}
+	w = serve(e, http.MethodGet, "/e/x", nil, map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)})
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("status=%d body=%q", w.Code, w.Body.String())
+	}
+	// 有 If-None-Match 时忽略 If-Modified-Since
+	w = serve(e, http.MethodGet, "/e/x", nil, map[string]string{
+		"If-None-Match":     `"v2"`,
+		"If-Modified-Since": modified.Format(http.TimeFormat),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+}
+
+func TestETagPassthrough(t *testing.T) {
+	large := strings.Repeat("a", 64)
+	tests := []struct {
+		name    string
+		method  string
+		handler HandlerFunc
+	}{
+		{"max size", http.MethodGet, func(c *Context) {
+			_, _ = c.W.Write([]byte(large[:32]))
+			_, _ = c.W.Write([]byte(large[32:]))
+		}},
+		{"flush", http.MethodGet, func(c *Context) {
+			_, _ = c.W.Write([]byte(large[:32]))
+			c.W.(http.Flusher).Flush()
+			_, _ = c.W.Write([]byte(large[32:]))
+		}},
+		{"status", http.MethodGet, func(c *Context) {
+			c.W.WriteHeader(http.StatusCreated)
+			_, _ = c.W.Write([]byte(large))
+		}},
+		{"post", http.MethodPost, func(c *Context) {
+			_, _ = c.W.Write([]byte(large))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine()
+			e.Group("e").Any("/x", tt.handler, ETag(ETagConfig{MaxSize: 48}))
+			w := serve(e, tt.method, "/e/x", nil, map[string]string{"If-None-Match": "*"})
+			if w.Header().Get("ETag") != "" || w.Code == http.StatusNotModified {
+				t.Fatalf("status=%d etag=%q", w.Code, w.Header().Get("ETag"))
+			}
+			if w.Body.String() != large {
+				t.Fatalf("body = %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestETagHead(t *testing.T) {
+	e := newTestEngine()
+	e.Group("e").Any("/x", func(c *Context) {
+		c.W.Header().Set("Content-Length", "5")
+		if c.R.Method == http.MethodGet {
+			_, _ = c.W.Write([]byte("hello"))
+		}
+	}, ETag(ETagConfig{}))
+	// HEAD 不计算ETag 也不改写 Content-Length
+	w := serve(e, http.MethodHead, "/e/x", nil, map[string]string{"If-None-Match": "*"})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" || w.Header().Get("Content-Length") != "5" {
+		t.Fatalf("status=%d etag=%q length=%q", w.Code, w.Header().Get("ETag"), w.Header().Get("Content-Length"))
+	}
+}
@@ -33,6 +33,7 @@ func (s *SpxSession) QueryRow(sql string, data any, queryValues ...any) error {
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	cols, err := rows.Columns()
 	if err != nil {
 		return err
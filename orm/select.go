@@ -7,6 +7,7 @@ func (s *SpxSession) SelectOne(data any, fields ...string) error {
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if rows.Next() {
 		_, err = s.nextFill(SelectOne, data, reflect.TypeOf(data), rows, cols)
 		if err != nil {
@@ -21,6 +22,7 @@ func (s *SpxSession) Select(data any, fields ...string) ([]any, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	result := make([]any, 0)
 	for rows.Next() {
 		res, err := s.nextFill(Select, data, reflect.TypeOf(data), rows, cols)
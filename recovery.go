@@ -5,38 +5,145 @@ import (
 	"fmt"
 	"gitbuh.com/spxzx/spxgo/serror"
 	"net/http"
+	"net/http/httputil"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
-func detailMsg(err any) string {
-	var pcs [32]uintptr
-	n := runtime.Callers(3, pcs[:])
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("%v", err))
-	for _, pc := range pcs[0:n] {
-		forPC := runtime.FuncForPC(pc)
-		file, line := forPC.FileLine(pc)
-		sb.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+const defaultStackDepth = 32
+
+// defaultSensitiveHeaders 打印请求时隐藏的请求头
+var defaultSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Csrf-Token"}
+
+// RecoveryHandler 自定义 panic 后的响应 rec 为 panic 的值
+type RecoveryHandler func(c *Context, rec any)
+
+type RecoveryConfig struct {
+	Handler          RecoveryHandler // 不为空时由它生成响应 否则返回500的 Problem
+	JSON             bool            // 返回 application/json {"error": "Internal Server Error"} 而不是 Problem
+	StackDepth       int             // 日志中堆栈的最大层数 默认32
+	Production       bool            // 生产环境 日志中不打印堆栈
+	SensitiveHeaders []string        // 日志中隐藏的请求头 在默认的 Authorization Cookie 等基础上追加
+}
+
+// stack 从发生 panic 的位置开始的调用栈 跳过 runtime 和 Recovery 自身的栈帧
+func stack(depth int) string {
+	pcs := make([]uintptr, depth+32)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var lines []string
+	panicked := false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			// gopanic 之前是 recover 所在的函数 之后才是发生 panic 的位置
+			panicked = true
+			lines = lines[:0]
+		case panicked && len(lines) == 0 && strings.HasPrefix(frame.Function, "runtime."):
+			// 空指针等运行时错误还会经过 runtime.panicmem runtime.sigpanic
+		case len(lines) < depth:
+			lines = append(lines, fmt.Sprintf("%s\n\t%s:%d", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
 	}
-	return sb.String()
+	return strings.Join(lines, "\n")
 }
 
+// isBrokenPipe 客户端已经断开 继续写入响应没有意义
+func isBrokenPipe(rec any) bool {
+	err, ok := rec.(error)
+	if !ok {
+		return false
+	}
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// dumpRequest 打印请求行和请求头 敏感的请求头替换为 *
+func dumpRequest(r *http.Request, sensitive []string) string {
+	req := *r
+	req.Header = r.Header.Clone()
+	for _, name := range sensitive {
+		if req.Header.Get(name) != "" {
+			req.Header.Set(name, "*")
+		}
+	}
+	dump, err := httputil.DumpRequest(&req, false)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(dump))
+}
+
+// Recovery 使用默认配置的 RecoveryWithConfig
 func Recovery(next HandlerFunc) HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{})(next)
+}
+
+// RecoveryWithConfig 捕获 panic 记录日志后按照配置生成500响应 用法 g.Use(RecoveryWithConfig(conf))
+func RecoveryWithConfig(conf RecoveryConfig) MiddlewareFunc {
+	if conf.StackDepth <= 0 {
+		conf.StackDepth = defaultStackDepth
+	}
+	sensitive := append(append([]string(nil), defaultSensitiveHeaders...), conf.SensitiveHeaders...)
+	return func(next HandlerFunc) HandlerFunc {
+		return recovery(conf, sensitive, next)
+	}
+}
+
+func recovery(conf RecoveryConfig, sensitive []string, next HandlerFunc) HandlerFunc {
 	return func(c *Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				err2 := err.(error)
-				if err2 != nil {
-					var spxError *serror.SpxError
-					if errors.As(err2, &spxError) {
-						spxError.ExecuteResult()
-						return
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// net/http 用它静默地中断响应 交还给 net/http
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			// panic 的值不一定是 error 比如 panic("...")
+			if err, ok := rec.(error); ok {
+				var spxError *serror.SpxError
+				if errors.As(err, &spxError) {
+					// Put 产生的错误 是预期内的 不需要打印堆栈
+					if !spxError.ExecuteResult() {
+						_ = c.Error(spxError)
+						c.handleErrors()
 					}
+					return
 				}
-				// 在debug bindJson 的时候发生了意料之外的情况，捕获了错误，但是并没有走下面的代码
-				c.Logger.Error(detailMsg(err))
-				c.Fail(http.StatusInternalServerError, "500 Internal Server Error")
+			}
+			brokenPipe := isBrokenPipe(rec)
+			if c.Logger != nil {
+				var sb strings.Builder
+				sb.WriteString(fmt.Sprintf("panic recovered: %v", rec))
+				if brokenPipe {
+					sb.WriteString(" (client disconnected)")
+				}
+				if dump := dumpRequest(c.R, sensitive); dump != "" {
+					sb.WriteString("\n" + dump)
+				}
+				// 断开连接不是程序的问题 不需要堆栈
+				if !conf.Production && !brokenPipe {
+					sb.WriteString("\n" + stack(conf.StackDepth))
+				}
+				c.Logger.Error(sb.String())
+			}
+			// 连接已经断开 或者已经写入了响应 不再写入
+			if brokenPipe || c.Written() {
+				return
+			}
+			switch {
+			case conf.Handler != nil:
+				conf.Handler(c, rec)
+			case conf.JSON:
+				_ = c.JSON(http.StatusInternalServerError, map[string]any{"error": http.StatusText(http.StatusInternalServerError)})
+			default:
+				_ = c.Problem(serror.NewProblem(http.StatusInternalServerError, ""))
 			}
 		}()
 		next(c)
@@ -0,0 +1,141 @@
+package spxgo
+
+import (
+	"fmt"
+	spxLog "gitbuh.com/spxzx/spxgo/log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// logOutput 日志写入临时文件 Logger 只支持 *os.File
+type logOutput struct {
+	file *os.File
+}
+
+func (o *logOutput) String() string {
+	data, _ := os.ReadFile(o.file.Name())
+	return string(data)
+}
+
+// recoveryLog 把 e 的错误日志写入临时文件
+func recoveryLog(t *testing.T, e *Engine) *logOutput {
+	file, err := os.CreateTemp(t.TempDir(), "recovery*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = file.Close()
+	})
+	e.Logger = &spxLog.Logger{
+		Formatter:   &spxLog.TextFormatter{},
+		Level:       spxLog.LevelDebug,
+		Outs:        []*spxLog.LoggerWriter{{Level: spxLog.LevelError, Out: file}},
+		LogFileSize: 1 << 20,
+	}
+	return &logOutput{file: file}
+}
+
+func TestRecoveryDump(t *testing.T) {
+	e := newTestEngine()
+	out := recoveryLog(t, e)
+	e.Group("r").Any("/x", func(c *Context) {
+		panic("boom")
+	}, RecoveryWithConfig(RecoveryConfig{SensitiveHeaders: []string{"X-Secret"}}))
+	r := httptest.NewRequest(http.MethodGet, "/r/x", nil)
+	r.Header.Set("Authorization", "Bearer token-value")
+	r.Header.Set("Cookie", "sid=cookie-value")
+	r.Header.Set("X-Secret", "secret-value")
+	r.Header.Set("X-Trace", "trace-value")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+		t.Fatalf("status = %d Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	log := out.String()
+	for _, value := range []string{"token-value", "cookie-value", "secret-value"} {
+		if strings.Contains(log, value) {
+			t.Errorf("sensitive value %q was logged:\n%s", value, log)
+		}
+	}
+	for _, want := range []string{"panic recovered: boom", "Authorization: *", "X-Secret: *", "trace-value", "TestRecoveryDump"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log does not contain %q:\n%s", want, log)
+		}
+	}
+	// 请求本身的请求头不会被修改
+	if r.Header.Get("Authorization") != "Bearer token-value" {
+		t.Fatalf("request header was modified: %q", r.Header.Get("Authorization"))
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	e := newTestEngine()
+	out := recoveryLog(t, e)
+	e.Group("r").Any("/x", func(c *Context) {
+		panic(http.ErrAbortHandler)
+	}, RecoveryWithConfig(RecoveryConfig{}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", rec)
+		}
+		if out.String() != "" {
+			t.Fatalf("ErrAbortHandler was logged: %s", out.String())
+		}
+	}()
+	serve(e, http.MethodGet, "/r/x", nil, nil)
+	t.Fatal("ErrAbortHandler was not re-panicked")
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	e := newTestEngine()
+	out := recoveryLog(t, e)
+	e.Group("r").Any("/x", func(c *Context) {
+		panic(fmt.Errorf("write tcp: %w", syscall.EPIPE))
+	}, RecoveryWithConfig(RecoveryConfig{}))
+	w := serve(e, http.MethodGet, "/r/x", nil, nil)
+	// 连接已经断开 不再写入响应
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Fatalf("response was written: status = %d body = %q", w.Code, w.Body.String())
+	}
+	log := out.String()
+	if !strings.Contains(log, "(client disconnected)") || strings.Contains(log, "TestRecoveryBrokenPipe") {
+		t.Fatalf("unexpected log:\n%s", log)
+	}
+}
+
+func TestRecoveryJSON(t *testing.T) {
+	e := newTestEngine()
+	out := recoveryLog(t, e)
+	e.Group("r").Any("/x", func(c *Context) {
+		var m map[string]int
+		m["a"] = 1 // 运行时错误
+	}, RecoveryWithConfig(RecoveryConfig{JSON: true, Production: true}))
+	w := serve(e, http.MethodGet, "/r/x", nil, nil)
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("status = %d Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"error":"Internal Server Error"}` {
+		t.Fatalf("body = %q", body)
+	}
+	// 生产环境不打印堆栈
+	if strings.Contains(out.String(), "TestRecoveryJSON") {
+		t.Fatalf("stack was logged in production:\n%s", out.String())
+	}
+}
+
+func TestRecoveryHandler(t *testing.T) {
+	e := newTestEngine()
+	e.Group("r").Any("/x", func(c *Context) {
+		panic("boom")
+	}, RecoveryWithConfig(RecoveryConfig{Handler: func(c *Context, rec any) {
+		_ = c.String(http.StatusServiceUnavailable, "%v", rec)
+	}}))
+	w := serve(e, http.MethodGet, "/r/x", nil, nil)
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "boom" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
@@ -1,11 +1,33 @@
 package render
 
 import (
+	"bytes"
+	"errors"
 	"gitbuh.com/spxzx/spxgo/internal/bytesconv"
 	"html/template"
 	"net/http"
 )
 
+// HTMLRender 根据模板名和数据生成一次渲染 Engine.HTMLRenderer 使用
+type HTMLRender interface {
+	Instance(name string, data any) Render
+}
+
+// Delims 模板的左右分隔符 为空时使用 {{ }}
+type Delims struct {
+	Left  string
+	Right string
+}
+
+// HTMLProduction 所有页面共用一个已经解析好的模板
+type HTMLProduction struct {
+	Template *template.Template
+}
+
+func (h *HTMLProduction) Instance(name string, data any) Render {
+	return &HTML{Name: name, Data: data, Template: h.Template, IsTemplate: true}
+}
+
 type HTML struct {
 	Name       string
 	Data       any
@@ -13,12 +35,20 @@ type HTML struct {
 	IsTemplate bool
 }
 
+// Render 模板先执行到缓冲区 执行失败时返回 *EncodeError 不写入半个页面
 func (h *HTML) Render(w http.ResponseWriter, statusCode int) error {
-	h.WriteContentType(w)
-	w.WriteHeader(statusCode)
 	if h.IsTemplate {
-		return h.Template.ExecuteTemplate(w, h.Name, h.Data)
+		if h.Template == nil {
+			return &EncodeError{Err: errors.New("html template not loaded")}
+		}
+		var buf bytes.Buffer
+		if err := h.Template.ExecuteTemplate(&buf, h.Name, h.Data); err != nil {
+			return &EncodeError{Err: err}
+		}
+		return writeBytes(w, statusCode, h, buf.Bytes())
 	}
+	h.WriteContentType(w)
+	w.WriteHeader(statusCode)
 	_, err := w.Write(bytesconv.StringToBytes(h.Data.(string)))
 	return err
 }
@@ -26,3 +56,16 @@ func (h *HTML) Render(w http.ResponseWriter, statusCode int) error {
 func (h *HTML) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, "text/html; charset=utf-8")
 }
+
+// htmlError 找不到模板或者解析失败 渲染时返回错误
+type htmlError struct {
+	err error
+}
+
+func (h *htmlError) Render(http.ResponseWriter, int) error {
+	return &EncodeError{Err: h.err}
+}
+
+func (h *htmlError) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "text/html; charset=utf-8")
+}
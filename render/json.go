@@ -1,25 +1,170 @@
 package render
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"fmt"
+	"gitbuh.com/spxzx/spxgo/codec"
 	"net/http"
+	"regexp"
+	"unicode/utf8"
 )
 
+const jsonContentType = "application/json; charset=utf-8"
+
+// 以下json渲染器都使用 codec.JSON 先编码 编码失败时返回 *EncodeError 不写入任何内容
+
 type JSON struct {
 	Data any
 }
 
 func (j *JSON) Render(w http.ResponseWriter, statusCode int) error {
-	j.WriteContentType(w)
-	w.WriteHeader(statusCode)
-	jsonData, err := json.Marshal(j.Data) // 数据编码转byte字符
+	jsonData, err := codec.JSON.Marshal(j.Data) // 数据编码转byte字符
 	if err != nil {
-		return err
+		return &EncodeError{Err: err}
 	}
-	_, err = w.Write(jsonData) // 调用Write后会自动调用一次WriteHeader(),而WriteHeader()不能重复调用,否则会报错
-	return err
+	// 调用Write后会自动调用一次WriteHeader(),而WriteHeader()不能重复调用,否则会报错
+	return writeBytes(w, statusCode, j, jsonData)
 }
 
 func (j *JSON) WriteContentType(w http.ResponseWriter) {
-	writeContentType(w, "application/json; charset=utf-8")
+	writeContentType(w, jsonContentType)
+}
+
+// IndentedJSON 带缩进的json 方便调试时阅读
+type IndentedJSON struct {
+	Data any
+}
+
+func (j *IndentedJSON) Render(w http.ResponseWriter, statusCode int) error {
+	jsonData, err := codec.JSON.MarshalIndent(j.Data, "", "    ")
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, j, jsonData)
+}
+
+func (j *IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+const defaultSecureJSONPrefix = "while(1);"
+
+// SecureJSON 数据为数组时加上前缀 防止老浏览器通过 <script> 劫持json
+type SecureJSON struct {
+	Prefix string // 为空时使用 while(1);
+	Data   any
+}
+
+func (j *SecureJSON) Render(w http.ResponseWriter, statusCode int) error {
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	if bytes.HasPrefix(jsonData, []byte("[")) && bytes.HasSuffix(jsonData, []byte("]")) {
+		prefix := j.Prefix
+		if prefix == "" {
+			prefix = defaultSecureJSONPrefix
+		}
+		jsonData = append([]byte(prefix), jsonData...)
+	}
+	return writeBytes(w, statusCode, j, jsonData)
+}
+
+func (j *SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// AsciiJSON 非ASCII字符转成 \uXXXX
+type AsciiJSON struct {
+	Data any
+}
+
+func (j *AsciiJSON) Render(w http.ResponseWriter, statusCode int) error {
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(jsonData))
+	for _, r := range string(jsonData) {
+		if r < utf8.RuneSelf {
+			buf.WriteByte(byte(r))
+			continue
+		}
+		// 超出基本平面的字符使用代理对
+		if r > 0xFFFF {
+			r -= 0x10000
+			_, _ = fmt.Fprintf(&buf, "\\u%04x\\u%04x", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		_, _ = fmt.Fprintf(&buf, "\\u%04x", r)
+	}
+	return writeBytes(w, statusCode, j, buf.Bytes())
+}
+
+func (j *AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/json")
+}
+
+// PureJSON 不转义 < > & 等HTML字符
+type PureJSON struct {
+	Data any
+}
+
+func (j *PureJSON) Render(w http.ResponseWriter, statusCode int) error {
+	var buf bytes.Buffer
+	encoder := codec.JSON.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(j.Data); err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, j, buf.Bytes())
+}
+
+func (j *PureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+var (
+	ErrInvalidCallback = errors.New("render: invalid jsonp callback")
+	// callbackPattern js标识符 允许 a.b.c 这样的属性访问 不允许括号等可以执行代码的字符
+	callbackPattern = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+)
+
+// ValidCallback 检查jsonp回调函数名 防止注入脚本
+func ValidCallback(callback string) bool {
+	return len(callback) <= 128 && callbackPattern.MatchString(callback)
+}
+
+// JSONP 回调名为空时和 JSON 一样
+type JSONP struct {
+	Callback string
+	Data     any
+}
+
+func (j *JSONP) Render(w http.ResponseWriter, statusCode int) error {
+	if j.Callback == "" {
+		return (&JSON{Data: j.Data}).Render(w, statusCode)
+	}
+	if !ValidCallback(j.Callback) {
+		return ErrInvalidCallback
+	}
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	// 开头的注释防止 Rosetta Flash 这类把响应当作flash执行的攻击
+	data := make([]byte, 0, len(j.Callback)+len(jsonData)+8)
+	data = append(data, "/**/"...)
+	data = append(data, j.Callback...)
+	data = append(data, '(')
+	data = append(data, jsonData...)
+	data = append(data, ");"...)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	return writeBytes(w, statusCode, j, data)
+}
+
+func (j *JSONP) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/javascript; charset=utf-8")
 }
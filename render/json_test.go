@@ -0,0 +1,80 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONRenderers(t *testing.T) {
+	tests := []struct {
+		name        string
+		render      Render
+		body        string
+		contentType string
+	}{
+		{"indented", &IndentedJSON{Data: map[string]int{"a": 1}}, "{\n    \"a\": 1\n}", jsonContentType},
+		{"secure array", &SecureJSON{Data: []int{1, 2}}, "while(1);[1,2]", jsonContentType},
+		{"secure prefix", &SecureJSON{Prefix: ")]}',\n", Data: []string{"a"}}, ")]}',\n[\"a\"]", jsonContentType},
+		// 只有顶层是数组时才加前缀
+		{"secure object", &SecureJSON{Data: map[string][]int{"a": {1}}}, `{"a":[1]}`, jsonContentType},
+		{"secure string", &SecureJSON{Data: "[1]"}, `"[1]"`, jsonContentType},
+		{"ascii", &AsciiJSON{Data: map[string]string{"a": "中文é"}}, `{"a":"\u4e2d\u6587\u00e9"}`, "application/json"},
+		{"ascii surrogate pair", &AsciiJSON{Data: "😀𝄞"}, `"\ud83d\ude00\ud834\udd1e"`, "application/json"},
+		{"pure", &PureJSON{Data: map[string]string{"html": "<b>&</b>"}}, "{\"html\":\"<b>&</b>\"}\n", jsonContentType},
+		{"jsonp", &JSONP{Callback: "app.cb", Data: []int{1}}, "/**/app.cb([1]);", "application/javascript; charset=utf-8"},
+		{"jsonp without callback", &JSONP{Data: []int{1}}, "[1]", jsonContentType},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if err := tt.render.Render(w, http.StatusOK); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if w.Body.String() != tt.body || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: body = %q Content-Type = %q, want %q %q", tt.name, w.Body.String(), w.Header().Get("Content-Type"), tt.body, tt.contentType)
+		}
+	}
+	// JSON 默认会转义 html 字符
+	w := httptest.NewRecorder()
+	_ = (&JSON{Data: "<b>"}).Render(w, http.StatusOK)
+	if strings.Contains(w.Body.String(), "<") {
+		t.Fatalf("JSON did not escape html: %q", w.Body.String())
+	}
+}
+
+func TestValidCallback(t *testing.T) {
+	for _, callback := range []string{"cb", "$", "_a1", "jQuery.fn.cb"} {
+		if !ValidCallback(callback) {
+			t.Errorf("ValidCallback(%q) = false", callback)
+		}
+	}
+	for _, callback := range []string{"", "1cb", "alert(1)", "a.", "a b", "a;b", "a[0]", strings.Repeat("a", 129)} {
+		if ValidCallback(callback) {
+			t.Errorf("ValidCallback(%q) = true", callback)
+		}
+	}
+	w := httptest.NewRecorder()
+	if err := (&JSONP{Callback: "alert(1)", Data: 1}).Render(w, http.StatusOK); !errors.Is(err, ErrInvalidCallback) || w.Body.Len() != 0 {
+		t.Fatalf("err = %v body = %q", err, w.Body.String())
+	}
+}
+
+func TestJSONEncodeError(t *testing.T) {
+	data := map[string]any{"ch": make(chan int)}
+	renders := []Render{
+		&JSON{Data: data}, &IndentedJSON{Data: data}, &SecureJSON{Data: data},
+		&AsciiJSON{Data: data}, &PureJSON{Data: data}, &JSONP{Callback: "cb", Data: data},
+	}
+	for _, r := range renders {
+		w := httptest.NewRecorder()
+		var encodeErr *EncodeError
+		if err := r.Render(w, http.StatusOK); !errors.As(err, &encodeErr) {
+			t.Errorf("%T: err = %v, want *EncodeError", r, err)
+		}
+		if w.Body.Len() != 0 || len(w.Header()) != 0 {
+			t.Errorf("%T: response was written: %v %q", r, w.Header(), w.Body.String())
+		}
+	}
+}
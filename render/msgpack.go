@@ -0,0 +1,22 @@
+package render
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+type MsgPack struct {
+	Data any
+}
+
+func (m *MsgPack) Render(w http.ResponseWriter, statusCode int) error {
+	data, err := msgpack.Marshal(m.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, m, data)
+}
+
+func (m *MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/msgpack")
+}
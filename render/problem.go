@@ -0,0 +1,42 @@
+package render
+
+import (
+	"encoding/xml"
+	"gitbuh.com/spxzx/spxgo/codec"
+	"gitbuh.com/spxzx/spxgo/serror"
+	"net/http"
+)
+
+// ProblemJSON 以 application/problem+json 输出 serror.Problem
+type ProblemJSON struct {
+	Data *serror.Problem
+}
+
+func (p *ProblemJSON) Render(w http.ResponseWriter, statusCode int) error {
+	data, err := codec.JSON.Marshal(p.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, p, data)
+}
+
+func (p *ProblemJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, serror.MIMEProblemJSON+"; charset=utf-8")
+}
+
+// ProblemXML 以 application/problem+xml 输出 serror.Problem
+type ProblemXML struct {
+	Data *serror.Problem
+}
+
+func (p *ProblemXML) Render(w http.ResponseWriter, statusCode int) error {
+	data, err := xml.Marshal(p.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, p, data)
+}
+
+func (p *ProblemXML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, serror.MIMEProblemXML+"; charset=utf-8")
+}
@@ -0,0 +1,27 @@
+package render
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+)
+
+type ProtoBuf struct {
+	Data any // 必须是 proto.Message
+}
+
+func (p *ProtoBuf) Render(w http.ResponseWriter, statusCode int) error {
+	msg, ok := p.Data.(proto.Message)
+	if !ok {
+		return &EncodeError{Err: errors.New("data is not proto.Message")}
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, p, data)
+}
+
+func (p *ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-protobuf")
+}
@@ -11,3 +11,24 @@ func writeContentType(w http.ResponseWriter, value string) {
 	// type Header map[string][]string
 	w.Header().Set("Content-Type", value)
 }
+
+// EncodeError 编码数据失败 此时还没有写入任何响应 调用方可以改为返回500
+type EncodeError struct {
+	Err error
+}
+
+func (e *EncodeError) Error() string {
+	return "render: " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// writeBytes 编码成功后再写入 Content-Type 状态码和内容
+func writeBytes(w http.ResponseWriter, statusCode int, r Render, data []byte) error {
+	r.WriteContentType(w)
+	w.WriteHeader(statusCode)
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"github.com/BurntSushi/toml"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type codecUser struct {
+	Name string   `msgpack:"name" yaml:"name" toml:"name"`
+	Age  int      `msgpack:"age" yaml:"age" toml:"age"`
+	Tags []string `msgpack:"tags" yaml:"tags" toml:"tags"`
+}
+
+func TestRenderCodecs(t *testing.T) {
+	user := codecUser{Name: "bob", Age: 30, Tags: []string{"a", "b"}}
+	decodeTOML := func(data []byte, v any) error {
+		_, err := toml.NewDecoder(bytes.NewReader(data)).Decode(v)
+		return err
+	}
+	tests := []struct {
+		render      Render
+		contentType string
+		decode      func(data []byte, v any) error
+	}{
+		{&MsgPack{Data: user}, "application/msgpack", msgpack.Unmarshal},
+		{&YAML{Data: user}, "application/yaml; charset=utf-8", yaml.Unmarshal},
+		{&TOML{Data: user}, "application/toml; charset=utf-8", decodeTOML},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if err := tt.render.Render(w, http.StatusCreated); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%T: status = %d Content-Type = %q", tt.render, w.Code, w.Header().Get("Content-Type"))
+		}
+		var got codecUser
+		if err := tt.decode(w.Body.Bytes(), &got); err != nil || !reflect.DeepEqual(got, user) {
+			t.Errorf("%T: got %+v, %v", tt.render, got, err)
+		}
+	}
+}
+
+func TestRenderProtoBuf(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (&ProtoBuf{Data: wrapperspb.String("bob")}).Render(w, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	var got wrapperspb.StringValue
+	if err := proto.Unmarshal(w.Body.Bytes(), &got); err != nil || got.GetValue() != "bob" {
+		t.Fatalf("got %q, %v", got.GetValue(), err)
+	}
+	if w.Header().Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+
+	// 编码失败时什么都不写
+	w = httptest.NewRecorder()
+	err := (&ProtoBuf{Data: codecUser{Name: "bob"}}).Render(w, http.StatusOK)
+	var encodeErr *EncodeError
+	if !errors.As(err, &encodeErr) {
+		t.Fatalf("err = %v, want *EncodeError", err)
+	}
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Fatalf("response was written: %v %q", w.Header(), w.Body.String())
+	}
+}
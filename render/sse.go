@@ -0,0 +1,82 @@
+package render
+
+import (
+	"fmt"
+	"gitbuh.com/spxzx/spxgo/codec"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SSE Server-Sent Events 的一条事件
+type SSE struct {
+	Event string
+	Id    string
+	Retry uint // 客户端重连间隔 毫秒
+	Data  any  // string 原样输出 其它类型编码成json
+}
+
+// Render 不调用 WriteHeader，同一个响应中会多次渲染事件，第一次 Write 时会自动写入 200
+func (s *SSE) Render(w http.ResponseWriter, statusCode int) error {
+	s.WriteContentType(w)
+	return s.Encode(w)
+}
+
+func (s *SSE) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if header.Get("Content-Type") == "text/event-stream" {
+		return
+	}
+	writeContentType(w, "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // 关闭 nginx 的缓冲
+}
+
+// Encode 按照 event-stream 的格式写入一条事件
+func (s *SSE) Encode(w io.Writer) error {
+	var sb strings.Builder
+	if s.Id != "" {
+		sb.WriteString("id: " + escapeSSE(s.Id) + "\n")
+	}
+	if s.Event != "" {
+		sb.WriteString("event: " + escapeSSE(s.Event) + "\n")
+	}
+	if s.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", s.Retry))
+	}
+	var data string
+	switch v := s.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := codec.JSON.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	// 多行数据每一行都要带上 data: 前缀 单独的 \r 也是换行 不处理会被客户端当成新的字段
+	for _, line := range strings.Split(lineBreaks.Replace(data), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteSSEComment 写入注释行，客户端会忽略，常用作心跳
+func WriteSSEComment(w io.Writer, comment string) error {
+	_, err := io.WriteString(w, ": "+escapeSSE(comment)+"\n\n")
+	return err
+}
+
+// lineBreaks 把 \r\n 和 \r 统一成 \n
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// id event 这类字段中不能有换行
+func escapeSSE(s string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSSEEncode(t *testing.T) {
+	cases := []struct {
+		name  string
+		event SSE
+		want  string
+	}{
+		{"data only", SSE{Data: "hello"}, "data: hello\n\n"},
+		{"multi-line data", SSE{Data: "a\nb\r\nc"}, "data: a\ndata: b\ndata: c\n\n"},
+		{"all fields", SSE{Id: "7", Event: "update", Retry: 3000, Data: "x"}, "id: 7\nevent: update\nretry: 3000\ndata: x\n\n"},
+		{"newlines stripped from fields", SSE{Id: "1\n2", Event: "a\r\nb", Data: ""}, "id: 12\nevent: ab\ndata: \n\n"},
+		{"cr-only data", SSE{Data: "hello\revent: admin\rdata: injected"}, "data: hello\ndata: event: admin\ndata: data: injected\n\n"},
+		{"cr-only fields", SSE{Id: "1\r2", Event: "a\rdata: b", Data: ""}, "id: 12\nevent: adata: b\ndata: \n\n"},
+		{"json data", SSE{Data: map[string]int{"n": 1}}, "data: {\"n\":1}\n\n"},
+	}
+	for _, tc := range cases {
+		var sb strings.Builder
+		if err := tc.event.Encode(&sb); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if sb.String() != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, sb.String(), tc.want)
+		}
+	}
+}
+
+func TestWriteSSEComment(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteSSEComment(&sb, "ping\nx"); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != ": pingx\n\n" {
+		t.Fatalf("got %q", sb.String())
+	}
+}
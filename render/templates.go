@@ -0,0 +1,183 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Templates 多页面模板 每个页面单独解析成一个模板集合 页面之间同名的 define 互不影响
+//
+//	t := &render.Templates{FS: viewsFS, Layouts: []string{"layouts/base.html"}, Partials: []string{"partials/*.html"}}
+//	t.Add("index", "pages/index.html")
+//	engine.HTMLRenderer = t
+//	c.Template(200, "index", data)
+//
+// 有布局时从第一个布局文件开始执行 没有时从页面的第一个文件开始执行
+// 每个文件以相对 FS 的路径命名 比如 {{template "partials/nav.html" .}} 不同目录下的同名文件不会冲突
+type Templates struct {
+	FS       fs.FS    // 模板文件来源 比如 embed.FS 为空时使用当前目录
+	Layouts  []string // 所有页面共用的布局 支持通配符
+	Partials []string // 所有页面共用的片段 支持通配符
+	FuncMap  template.FuncMap
+	Delims   Delims
+	Debug    bool // 开发时使用 每次渲染前检查文件 有修改时重新解析
+
+	mutex sync.RWMutex
+	pages map[string]*page
+}
+
+type page struct {
+	patterns    []string
+	template    *template.Template
+	entry       string
+	fingerprint string // 所有文件的路径 大小和修改时间
+}
+
+// Add 添加一个页面 patterns 为页面自己的文件 支持通配符 会和 Layouts Partials 一起解析
+func (t *Templates) Add(name string, patterns ...string) error {
+	p := &page{patterns: patterns}
+	if err := t.parse(p); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.pages == nil {
+		t.pages = make(map[string]*page)
+	}
+	t.pages[name] = p
+	return nil
+}
+
+// MustAdd 同 Add 解析失败时 panic 用于启动时加载
+func (t *Templates) MustAdd(name string, patterns ...string) {
+	if err := t.Add(name, patterns...); err != nil {
+		panic(err)
+	}
+}
+
+func (t *Templates) Instance(name string, data any) Render {
+	t.mutex.RLock()
+	p, ok := t.pages[name]
+	t.mutex.RUnlock()
+	if !ok {
+		return &htmlError{err: fmt.Errorf("html template %q not found", name)}
+	}
+	if t.Debug {
+		if err := t.reload(p); err != nil {
+			return &htmlError{err: err}
+		}
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return &HTML{Name: p.entry, Data: data, Template: p.template, IsTemplate: true}
+}
+
+func (t *Templates) fsys() fs.FS {
+	if t.FS != nil {
+		return t.FS
+	}
+	return os.DirFS(".")
+}
+
+// files 展开通配符 布局在最前面 然后是片段和页面 同一个文件被多个通配符匹配时返回错误
+func (t *Templates) files(p *page) ([]string, error) {
+	var files []string
+	seen := make(map[string]string)
+	for _, patterns := range [][]string{t.Layouts, t.Partials, p.patterns} {
+		for _, pattern := range patterns {
+			matches, err := fs.Glob(t.fsys(), pattern)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("html template: pattern matches no files: %q", pattern)
+			}
+			for _, file := range matches {
+				if prev, ok := seen[file]; ok {
+					return nil, fmt.Errorf("html template: %q matched by both %q and %q", file, prev, pattern)
+				}
+				seen[file] = pattern
+			}
+			files = append(files, matches...)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("html template: no files")
+	}
+	return files, nil
+}
+
+func (t *Templates) fingerprint(files []string) (string, error) {
+	var b strings.Builder
+	for _, file := range files {
+		info, err := fs.Stat(t.fsys(), file)
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func (t *Templates) parse(p *page) error {
+	files, err := t.files(p)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := t.fingerprint(files)
+	if err != nil {
+		return err
+	}
+	tmpl, err := t.parseFiles(files)
+	if err != nil {
+		return err
+	}
+	p.template, p.entry, p.fingerprint = tmpl, files[0], fingerprint
+	return nil
+}
+
+// parseFiles 逐个读取已经展开的文件 以相对路径作为模板名
+// 不使用 ParseFS 它会把文件名再当作通配符匹配一次 并且只用文件名命名模板
+func (t *Templates) parseFiles(files []string) (*template.Template, error) {
+	root := template.New(files[0]).Delims(t.Delims.Left, t.Delims.Right).Funcs(t.FuncMap)
+	for _, file := range files {
+		b, err := fs.ReadFile(t.fsys(), file)
+		if err != nil {
+			return nil, err
+		}
+		tmpl := root
+		if file != root.Name() {
+			tmpl = root.New(file)
+		}
+		if _, err = tmpl.Parse(string(b)); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// reload 文件有增减或者修改时重新解析
+func (t *Templates) reload(p *page) error {
+	files, err := t.files(p)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := t.fingerprint(files)
+	if err != nil {
+		return err
+	}
+	t.mutex.RLock()
+	changed := fingerprint != p.fingerprint
+	t.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.parse(p)
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func renderPage(t *testing.T, tmpl *Templates, name string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := tmpl.Instance(name, "data").Render(w, 200); err != nil {
+		t.Fatal(err)
+	}
+	return w.Body.String()
+}
+
+func TestTemplatesRelativeNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<main>{{template "content" .}}</main>{{template "partials/nav.html"}}`)},
+		"partials/nav.html": {Data: []byte(`<nav/>`)},
+		"admin/index.html":  {Data: []byte(`{{define "content"}}admin {{.}}{{end}}`)},
+		"user/index.html":   {Data: []byte(`{{define "content"}}user {{.}}{{end}}`)},
+	}
+	tmpl := &Templates{FS: fsys, Layouts: []string{"layouts/*.html"}, Partials: []string{"partials/*.html"}}
+	// 不同目录下的同名文件不会互相覆盖
+	tmpl.MustAdd("admin", "admin/index.html")
+	tmpl.MustAdd("user", "user/index.html")
+	if got := renderPage(t, tmpl, "admin"); got != "<main>admin data</main><nav/>" {
+		t.Fatalf("admin = %q", got)
+	}
+	if got := renderPage(t, tmpl, "user"); got != "<main>user data</main><nav/>" {
+		t.Fatalf("user = %q", got)
+	}
+	if err := tmpl.Instance("missing", nil).Render(httptest.NewRecorder(), 200); err == nil {
+		t.Fatal("expected error for missing page")
+	}
+}
+
+func TestTemplatesNoLayout(t *testing.T) {
+	fsys := fstest.MapFS{"pages/[id].html": {Data: []byte(`page [[.]]`)}}
+	tmpl := &Templates{FS: fsys, Delims: Delims{Left: "[[", Right: "]]"}}
+	// 文件名中的通配符字符不会被再次当作通配符
+	if err := tmpl.Add("page", "pages/*.html"); err != nil {
+		t.Fatal(err)
+	}
+	if got := renderPage(t, tmpl, "page"); got != "page data" {
+		t.Fatalf("page = %q", got)
+	}
+}
+
+func TestTemplatesDuplicate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`{{template "content" .}}`)},
+		"index.html":        {Data: []byte(`{{define "content"}}x{{end}}`)},
+	}
+	tmpl := &Templates{FS: fsys, Layouts: []string{"layouts/base.html"}}
+	err := tmpl.Add("index", "index.html", "*.html")
+	if err == nil || !strings.Contains(err.Error(), "matched by both") {
+		t.Fatalf("err = %v, want duplicate error", err)
+	}
+	if err = tmpl.Add("index", "missing/*.html"); err == nil {
+		t.Fatal("expected error for pattern without matches")
+	}
+}
+
+func TestTemplatesDebugReload(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte(`v1`)}}
+	tmpl := &Templates{FS: fsys, Debug: true}
+	tmpl.MustAdd("index", "index.html")
+	if got := renderPage(t, tmpl, "index"); got != "v1" {
+		t.Fatalf("index = %q", got)
+	}
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`version 2`)}
+	if got := renderPage(t, tmpl, "index"); got != "version 2" {
+		t.Fatalf("reloaded index = %q", got)
+	}
+}
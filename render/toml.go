@@ -0,0 +1,23 @@
+package render
+
+import (
+	"bytes"
+	"github.com/BurntSushi/toml"
+	"net/http"
+)
+
+type TOML struct {
+	Data any // 顶层必须是结构体或map
+}
+
+func (t *TOML) Render(w http.ResponseWriter, statusCode int) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(t.Data); err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, t, buf.Bytes())
+}
+
+func (t *TOML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/toml; charset=utf-8")
+}
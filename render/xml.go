@@ -10,9 +10,11 @@ type XML struct {
 }
 
 func (x *XML) Render(w http.ResponseWriter, statusCode int) error {
-	x.WriteContentType(w)
-	w.WriteHeader(statusCode)
-	return xml.NewEncoder(w).Encode(x.Data)
+	xmlData, err := xml.Marshal(x.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, x, xmlData)
 }
 
 func (x *XML) WriteContentType(w http.ResponseWriter) {
@@ -0,0 +1,22 @@
+package render
+
+import (
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type YAML struct {
+	Data any
+}
+
+func (y *YAML) Render(w http.ResponseWriter, statusCode int) error {
+	data, err := yaml.Marshal(y.Data)
+	if err != nil {
+		return &EncodeError{Err: err}
+	}
+	return writeBytes(w, statusCode, y, data)
+}
+
+func (y *YAML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/yaml; charset=utf-8")
+}
@@ -0,0 +1,102 @@
+package spxgo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// writtenReporter 包装 ResponseWriter 的中间件(Compress ETag 等)可以在内容还缓存在自己这里时报告已经写入
+type writtenReporter interface {
+	Written() bool
+}
+
+// responseWriter ServeHTTP 中包装原始的 ResponseWriter 记录是否已经写入了响应
+// 直接操作 c.W 的写入(Stream SSE Hijack)不会设置 StatusCode 只能在这里发现
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *responseWriter) reset(rw http.ResponseWriter) {
+	w.ResponseWriter = rw
+	w.status = 0
+	w.written = false
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// 1xx(比如 103 Early Hints)之后还可以写入最终的响应 101 除外
+	if !w.written && (status >= http.StatusOK || status == http.StatusSwitchingProtocols) {
+		w.status = status
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.markWritten()
+	return w.ResponseWriter.Write(p)
+}
+
+// ReadFrom 保留 net/http 的 sendfile 优化
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.markWritten()
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+func (w *responseWriter) markWritten() {
+	if !w.written {
+		w.status = http.StatusOK
+		w.written = true
+	}
+}
+
+func (w *responseWriter) Flush() {
+	w.markWritten()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not supported")
+	}
+	w.written = true
+	return hijacker.Hijack()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Written 是否已经写入了响应 包括通过 c.W 直接写入 Flush 和 Hijack
+// 沿着中间件包装的 ResponseWriter 逐层检查 内容还缓存在 Compress 等中间件中时也算已经写入
+func (c *Context) Written() bool {
+	if c.StatusCode != 0 {
+		return true
+	}
+	w := c.W
+	for w != nil {
+		if reporter, ok := w.(writtenReporter); ok && reporter.Written() {
+			return true
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = unwrapper.Unwrap()
+	}
+	return false
+}
@@ -1,7 +1,16 @@
 package serror
 
+import (
+	"errors"
+	"net/http"
+)
+
+// SpxError 带有状态码和业务错误码的错误 可以作为 HandlerFuncE 的返回值
+// 也可以通过 Context.ErrorCheck 取得当前请求的实例 用 Put 检查错误 错误不为空时中断处理函数
 type SpxError struct {
 	err       error
+	Status    int // 响应的状态码 0 表示500
+	Code      int // 业务错误码 0 不输出
 	ErrorFunc ErrorFunc
 }
 
@@ -9,28 +18,77 @@ func Default() *SpxError {
 	return &SpxError{}
 }
 
+// New 创建一个错误 msg 为错误内容
+func New(status, code int, msg string) *SpxError {
+	return &SpxError{err: errors.New(msg), Status: status, Code: code}
+}
+
+// Wrap 给 err 加上状态码和业务错误码 err 为空时返回 nil
+// 返回 error 而不是 *SpxError 避免空指针被当作非空的 error 返回
+func Wrap(err error, status, code int) error {
+	if err == nil {
+		return nil
+	}
+	return &SpxError{err: err, Status: status, Code: code}
+}
+
 func (e *SpxError) Error() string {
+	if e.err == nil {
+		return http.StatusText(e.StatusCode())
+	}
 	return e.err.Error()
 }
 
+// Unwrap 使 errors.Is 和 errors.As 可以检查被包装的错误
+func (e *SpxError) Unwrap() error {
+	return e.err
+}
+
+// StatusCode 没有设置状态码时返回500
+func (e *SpxError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// WithStatus 设置之后 Put 的错误使用的状态码
+func (e *SpxError) WithStatus(status int) *SpxError {
+	e.Status = status
+	return e
+}
+
+// WithCode 设置之后 Put 的错误使用的业务错误码
+func (e *SpxError) WithCode(code int) *SpxError {
+	e.Code = code
+	return e
+}
+
+// Put err 不为空时 panic 交给 Recovery 处理
+// panic 的是带有 err 的副本 e 本身不会被修改 多个请求共用同一个 SpxError 也是安全的
 func (e *SpxError) Put(err error) {
 	e.check(err)
 }
 
 func (e *SpxError) check(err error) {
 	if err != nil {
-		e.err = err
-		panic(e)
+		panic(&SpxError{err: err, Status: e.Status, Code: e.Code, ErrorFunc: e.ErrorFunc})
 	}
 }
 
 type ErrorFunc func(spxError *SpxError)
 
 // Result 暴露一个方法让用户自定义
+// 不设置时 Recovery 把错误交给 Engine 的 ErrorHandler 默认返回对应状态码的 Problem
 func (e *SpxError) Result(ef ErrorFunc) {
 	e.ErrorFunc = ef
 }
 
-func (e *SpxError) ExecuteResult() {
+// ExecuteResult 执行 Result 设置的方法 没有设置时返回 false
+func (e *SpxError) ExecuteResult() bool {
+	if e.ErrorFunc == nil {
+		return false
+	}
 	e.ErrorFunc(e)
+	return true
 }
@@ -0,0 +1,73 @@
+package serror
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"testing"
+)
+
+// catch 执行 fn 返回 panic 的 *SpxError
+func catch(t *testing.T, fn func()) (spxError *SpxError) {
+	t.Helper()
+	defer func() {
+		if rec := recover(); rec != nil {
+			var ok bool
+			if spxError, ok = rec.(*SpxError); !ok {
+				t.Fatalf("panic value = %#v", rec)
+			}
+		}
+	}()
+	fn()
+	return nil
+}
+
+func TestUnwrap(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}
+	err := Wrap(pathErr, http.StatusNotFound, 1001)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("errors.Is did not see the wrapped error")
+	}
+	var target *fs.PathError
+	if !errors.As(err, &target) || target != pathErr {
+		t.Fatal("errors.As did not find *fs.PathError")
+	}
+	var spxError *SpxError
+	if !errors.As(err, &spxError) || spxError.StatusCode() != http.StatusNotFound || spxError.Code != 1001 || err.Error() != pathErr.Error() {
+		t.Fatalf("err = %#v", err)
+	}
+	if Wrap(nil, http.StatusNotFound, 1) != nil {
+		t.Fatal("Wrap(nil) should return nil")
+	}
+	e := New(http.StatusConflict, 2002, "name taken")
+	if e.Error() != "name taken" || e.StatusCode() != http.StatusConflict || errors.Unwrap(e) == nil {
+		t.Fatalf("New = %#v", e)
+	}
+	if Default().StatusCode() != http.StatusInternalServerError || Default().Error() != "Internal Server Error" {
+		t.Fatal("Default should report 500")
+	}
+}
+
+func TestPut(t *testing.T) {
+	// 多个请求共用的实例
+	shared := Default().WithStatus(http.StatusBadRequest).WithCode(7)
+	if catch(t, func() { shared.Put(nil) }) != nil {
+		t.Fatal("Put(nil) panicked")
+	}
+	first, second := errors.New("first"), errors.New("second")
+	got1 := catch(t, func() { shared.Put(first) })
+	got2 := catch(t, func() { shared.Put(second) })
+	if got1 == nil || got2 == nil || got1 == shared || got2 == shared {
+		t.Fatalf("Put should panic with a copy: %v %v", got1, got2)
+	}
+	if !errors.Is(got1, first) || errors.Is(got1, second) || !errors.Is(got2, second) {
+		t.Fatalf("got %v and %v", got1, got2)
+	}
+	if got1.Status != http.StatusBadRequest || got1.Code != 7 {
+		t.Fatalf("copy lost status or code: %#v", got1)
+	}
+	// 共用的实例没有被修改
+	if shared.Unwrap() != nil || shared.Error() != http.StatusText(http.StatusBadRequest) {
+		t.Fatalf("shared instance was modified: %v", shared.Unwrap())
+	}
+}
@@ -0,0 +1,212 @@
+package serror
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	MIMEProblemJSON = "application/problem+json"
+	MIMEProblemXML  = "application/problem+xml"
+
+	// problemNamespace RFC 9457 附录中 xml 格式使用的命名空间
+	problemNamespace = "urn:ietf:rfc:7807"
+)
+
+// Problem RFC 9457 Problem Details 统一的错误响应格式
+// Extensions 中的字段和标准字段平铺输出 不能和标准字段重名
+type Problem struct {
+	Type       string // 问题类型的URI 为空时表示 about:blank
+	Title      string // 问题类型的简短说明 about:blank 时为状态码对应的文本
+	Status     int
+	Detail     string // 本次问题的具体说明
+	Instance   string // 本次问题的URI 一般为请求路径
+	Extensions map[string]any
+}
+
+// NewProblem 类型为 about:blank 标题为状态码对应的文本
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With 添加扩展字段 返回自身方便链式调用
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Problem 也可以作为错误返回 交给 ErrorHandler
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+	}
+	return fmt.Sprintf("%d %s", p.Status, p.Title)
+}
+
+// fields 标准字段 空值不输出
+func (p *Problem) fields() map[string]any {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	set := func(key, value string) {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	set("type", p.Type)
+	set("title", p.Title)
+	set("detail", p.Detail)
+	set("instance", p.Instance)
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	return m
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.fields())
+}
+
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*p = Problem{}
+	targets := map[string]any{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+	for k, raw := range m {
+		if target, ok := targets[k]; ok {
+			if err := json.Unmarshal(raw, target); err != nil {
+				return err
+			}
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		p.With(k, v)
+	}
+	return nil
+}
+
+// MarshalXML 根元素为 problem 扩展字段按名称排序 值使用 xml.Marshal 的规则编码
+func (p *Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Local: "problem"}, Attr: []xml.Attr{
+		{Name: xml.Name{Local: "xmlns"}, Value: problemNamespace},
+	}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	fields := p.fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	// 标准字段在前
+	order := map[string]int{"type": 1, "title": 2, "status": 3, "detail": 4, "instance": 5}
+	sort.Slice(keys, func(i, j int) bool {
+		oi, oj := order[keys[i]], order[keys[j]]
+		if oi == 0 {
+			oi = len(order) + 1
+		}
+		if oj == 0 {
+			oj = len(order) + 1
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		if err := encodeXMLElement(e, k, fields[k]); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeXMLElement xml.Marshal 不支持 map 这里把任意 map 展开为子元素 键排序后作为元素名
+// 切片按照 RFC 7807 附录的格式 每个元素为一个 <i> 子元素 UnmarshalJSON 得到的 []any 就是这种形式
+// 不是合法元素名的键输出为 <extension name="..."> 名字作为属性会被转义
+func encodeXMLElement(e *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{Name: xml.Name{Local: "extension"}, Attr: []xml.Attr{
+			{Name: xml.Name{Local: "name"}, Value: name},
+		}}
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			k := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, k)
+			values[k] = iter.Value().Interface()
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeXMLElement(e, k, values[k]); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		// []byte 由 xml 包按文本输出
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXMLElement(e, "i", v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(value, start)
+}
+
+// isXMLName 只接受不带命名空间前缀的元素名 xml 开头的名字是保留的
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
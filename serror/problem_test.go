@@ -0,0 +1,83 @@
+package serror
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestProblemXMLNames(t *testing.T) {
+	p := NewProblem(http.StatusBadRequest, "").
+		With("trace_id", "abc").
+		With("bad name", 1).
+		With("<x>", "y").
+		With("xmlns", "z").
+		With("2fa", true)
+	data, err := xml.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`<trace_id>abc</trace_id>`,
+		`<extension name="2fa">true</extension>`,
+		`<extension name="&lt;x&gt;">y</extension>`,
+		`<extension name="bad name">1</extension>`,
+		`<extension name="xmlns">z</extension>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("xml = %s, missing %s", got, want)
+		}
+	}
+	// 输出的必须是合法的 xml
+	var v struct{}
+	if err := xml.Unmarshal(data, &v); err != nil {
+		t.Fatalf("invalid xml %s: %v", got, err)
+	}
+}
+
+func TestProblemXMLArray(t *testing.T) {
+	// 从json还原的 Problem 数组元素为 map[string]any
+	var p Problem
+	if err := json.Unmarshal([]byte(`{"status":422,"errors":[{"field":"name","tags":["required"]},{"field":"age"}],"ids":[1,2]}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`<errors><i><field>name</field><tags><i>required</i></tags></i><i><field>age</field></i></errors>`,
+		`<ids><i>1</i><i>2</i></ids>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("xml = %s, missing %s", got, want)
+		}
+	}
+}
+
+func TestProblemXMLTypedExtensions(t *testing.T) {
+	p := NewProblem(http.StatusBadRequest, "").
+		With("fields", map[string]string{"name": "required", "age": "min"}).
+		With("counts", map[string]int{"b": 2, "a": 1}).
+		With("tags", []string{"x", "y"}).
+		With("raw", []byte("abc"))
+	data, err := xml.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`<fields><age>min</age><name>required</name></fields>`,
+		`<counts><a>1</a><b>2</b></counts>`,
+		`<tags><i>x</i><i>y</i></tags>`,
+		`<raw>abc</raw>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("xml = %s, missing %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const filePrefix = "sess_"
+
+var ErrInvalidID = errors.New("invalid session id")
+
+// FileStore 每个会话一个文件 文件前8个字节是过期时间
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (f *FileStore) path(id string) (string, error) {
+	// id 会拼接到路径中 不允许出现路径分隔符
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", ErrInvalidID
+	}
+	return filepath.Join(f.Dir, filePrefix+id), nil
+}
+
+func (f *FileStore) Load(id string) ([]byte, bool, error) {
+	path, err := f.path(id)
+	if err != nil {
+		return nil, false, err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(content) < 8 {
+		return nil, false, f.Delete(id)
+	}
+	expiresAt := time.Unix(0, int64(binary.BigEndian.Uint64(content)))
+	if !time.Now().Before(expiresAt) {
+		return nil, false, f.Delete(id)
+	}
+	return content[8:], true, nil
+}
+
+// Save 先写临时文件再重命名 避免并发读到写了一半的文件
+func (f *FileStore) Save(id string, data []byte, expiresAt time.Time) error {
+	path, err := f.path(id)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.Dir, filePrefix+"tmp")
+	if err != nil {
+		return err
+	}
+	content := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(data)), uint64(expiresAt.UnixNano()))
+	content = append(content, data...)
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *FileStore) Delete(id string) error {
+	path, err := f.path(id)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// GC 清理过期的会话文件 可以定时调用
+func (f *FileStore) GC() error {
+	entries, err := os.ReadDir(f.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) {
+			continue
+		}
+		// Load 会删除过期的文件
+		if _, _, err := f.Load(strings.TrimPrefix(name, filePrefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
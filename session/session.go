@@ -0,0 +1,278 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"gitbuh.com/spxzx/spxgo"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	contextKey  = "spxgo/session"
+	flashPrefix = "_flash."
+
+	defaultCookieName      = "spxgo_session"
+	defaultIdleTimeout     = 30 * time.Minute
+	defaultAbsoluteTimeout = 24 * time.Hour
+)
+
+var ErrNoStore = errors.New("session store is nil")
+
+// Options 会话中间件的配置
+type Options struct {
+	Store      Store
+	CookieName string
+	Path       string
+	Domain     string
+	Secure     bool
+	// AllowScriptAccess 为 true 时会话cookie不带 HttpOnly 前端脚本可以读取 默认不可读取
+	AllowScriptAccess bool
+	IdleTimeout       time.Duration // 多久没有访问就过期 默认30分钟
+	AbsoluteTimeout   time.Duration // 从创建开始最长存活时间 默认24小时
+}
+
+// record 存储到 Store 中的内容
+type record struct {
+	Values     map[string]any
+	CreatedAt  time.Time
+	LastAccess time.Time
+}
+
+// Session 一次请求中的会话 值类型如果不是基础类型需要先 gob.Register
+// 新会话在第一次写入时才下发cookie 处理函数没有使用会话时不会保存 匿名请求不会占用存储
+type Session struct {
+	mutex     sync.RWMutex
+	id        string
+	oldID     string // Regenerate 后需要从存储中删除的旧id
+	record    record
+	fresh     bool // 新建的会话 还没有下发cookie
+	accessed  bool // 本次请求使用过会话 需要保存
+	destroyed bool
+	opts      *Options
+	c         *spxgo.Context
+}
+
+// ID 新会话调用后会下发cookie 需要在写入响应体之前调用
+func (s *Session) ID() string {
+	s.touch()
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.id
+}
+
+func (s *Session) Get(key string) (any, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accessed = true
+	value, ok := s.record.Values[key]
+	return value, ok
+}
+
+// Set 新会话第一次写入时下发cookie 需要在写入响应体之前调用
+func (s *Session) Set(key string, value any) {
+	s.mutex.Lock()
+	s.record.Values[key] = value
+	s.mutex.Unlock()
+	s.touch()
+}
+
+func (s *Session) Delete(key string) {
+	s.mutex.Lock()
+	delete(s.record.Values, key)
+	s.mutex.Unlock()
+	s.touch()
+}
+
+// touch 标记会话需要保存 新会话此时才下发cookie
+func (s *Session) touch() {
+	s.mutex.Lock()
+	s.accessed = true
+	fresh := s.fresh
+	s.fresh = false
+	s.mutex.Unlock()
+	if fresh {
+		s.writeCookie()
+	}
+}
+
+// Flash 写入只能被读取一次的值 常用于重定向后的提示信息
+func (s *Session) Flash(key string, value any) {
+	s.Set(flashPrefix+key, value)
+}
+
+// GetFlash 读取 Flash 写入的值 读取后即删除
+func (s *Session) GetFlash(key string) (any, bool) {
+	s.mutex.Lock()
+	s.accessed = true
+	value, ok := s.record.Values[flashPrefix+key]
+	delete(s.record.Values, flashPrefix+key)
+	s.mutex.Unlock()
+	return value, ok
+}
+
+// Regenerate 更换会话id 保留数据 登录等权限变化后调用以防止会话固定攻击
+// 需要在写入响应体之前调用
+func (s *Session) Regenerate() error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	if s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = id
+	s.accessed = true
+	s.fresh = false
+	s.mutex.Unlock()
+	s.writeCookie()
+	return nil
+}
+
+// Destroy 删除会话数据并清除cookie 需要在写入响应体之前调用
+func (s *Session) Destroy() error {
+	s.mutex.Lock()
+	s.destroyed = true
+	s.record.Values = make(map[string]any)
+	id, oldID := s.id, s.oldID
+	s.mutex.Unlock()
+	s.c.SetCookie(s.opts.CookieName, "", -1, s.opts.Path, s.opts.Domain, s.opts.Secure, !s.opts.AllowScriptAccess)
+	if oldID != "" {
+		if err := s.opts.Store.Delete(oldID); err != nil {
+			return err
+		}
+	}
+	return s.opts.Store.Delete(id)
+}
+
+func (s *Session) writeCookie() {
+	// cookie 的有效期和绝对过期时间一致 空闲过期由服务端判断
+	s.mutex.RLock()
+	id, maxAge := s.id, int(time.Until(s.record.CreatedAt.Add(s.opts.AbsoluteTimeout)).Seconds())
+	s.mutex.RUnlock()
+	s.c.SetCookie(s.opts.CookieName, id, maxAge, s.opts.Path, s.opts.Domain, s.opts.Secure, !s.opts.AllowScriptAccess)
+}
+
+func (s *Session) expiresAt() time.Time {
+	idle := s.record.LastAccess.Add(s.opts.IdleTimeout)
+	absolute := s.record.CreatedAt.Add(s.opts.AbsoluteTimeout)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+func (s *Session) save() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// 没有使用过的会话和没有下发cookie的新会话不保存
+	if s.destroyed || s.fresh || !s.accessed {
+		return nil
+	}
+	if s.oldID != "" {
+		if err := s.opts.Store.Delete(s.oldID); err != nil {
+			return err
+		}
+		s.oldID = ""
+	}
+	s.record.LastAccess = time.Now()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&s.record); err != nil {
+		return err
+	}
+	return s.opts.Store.Save(s.id, buf.Bytes(), s.expiresAt())
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID 会话id来自客户端 文件存储会用它拼接路径 必须校验
+func validID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func (o *Options) load(c *spxgo.Context) (*Session, error) {
+	s := &Session{opts: o, c: c}
+	if id, err := c.Cookie(o.CookieName); err == nil && validID(id) {
+		data, ok, err := o.Store.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		if ok && gob.NewDecoder(bytes.NewReader(data)).Decode(&s.record) == nil {
+			s.id = id
+			now := time.Now()
+			if now.Before(s.expiresAt()) {
+				if s.record.Values == nil {
+					s.record.Values = make(map[string]any)
+				}
+				return s, nil
+			}
+			// 存储没有及时清理的过期会话
+			if err := o.Store.Delete(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s.id = id
+	s.record = record{Values: make(map[string]any), CreatedAt: now, LastAccess: now}
+	s.fresh = true
+	return s, nil
+}
+
+// Middleware 根据cookie中的会话id加载会话到 Context 中 处理函数使用过会话时在返回后保存
+func Middleware(opts Options) spxgo.MiddlewareFunc {
+	if opts.Store == nil {
+		panic(ErrNoStore)
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = defaultCookieName
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = defaultIdleTimeout
+	}
+	if opts.AbsoluteTimeout <= 0 {
+		opts.AbsoluteTimeout = defaultAbsoluteTimeout
+	}
+	return func(next spxgo.HandlerFunc) spxgo.HandlerFunc {
+		return func(c *spxgo.Context) {
+			s, err := opts.load(c)
+			if err != nil {
+				// 交给 ErrorHandler 生成响应
+				_ = c.Error(err)
+				return
+			}
+			c.Set(contextKey, s)
+			next(c)
+			// 响应已经写出 只会记录日志
+			_ = c.Error(s.save())
+		}
+	}
+}
+
+// Default 取出中间件加载的会话 没有使用中间件时返回 nil
+func Default(c *spxgo.Context) *Session {
+	value, ok := c.Get(contextKey)
+	if !ok {
+		return nil
+	}
+	return value.(*Session)
+}
@@ -0,0 +1,209 @@
+package session_test
+
+import (
+	"errors"
+	"gitbuh.com/spxzx/spxgo"
+	"gitbuh.com/spxzx/spxgo/session"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestEngine 不带日志等中间件 /s 下的路由读写会话
+func newTestEngine(store session.Store) *spxgo.Engine {
+	e := spxgo.New()
+	g := e.Group("s")
+	g.Use(session.Middleware(session.Options{Store: store}))
+	g.Get("/set", func(c *spxgo.Context) {
+		session.Default(c).Set("user", "bob")
+		_ = c.String(http.StatusOK, "ok")
+	})
+	g.Get("/ping", func(c *spxgo.Context) {
+		_ = c.String(http.StatusOK, "pong")
+	})
+	g.Get("/get", func(c *spxgo.Context) {
+		user, _ := session.Default(c).Get("user")
+		_ = c.String(http.StatusOK, "%v", user)
+	})
+	g.Get("/login", func(c *spxgo.Context) {
+		if err := session.Default(c).Regenerate(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		_ = c.String(http.StatusOK, "ok")
+	})
+	g.Get("/logout", func(c *spxgo.Context) {
+		if err := session.Default(c).Destroy(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		_ = c.String(http.StatusOK, "ok")
+	})
+	return e
+}
+
+// do 发送请求 返回响应和响应中的会话cookie
+func do(e *spxgo.Engine, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	r := httptest.NewRequest(http.MethodGet, "/s"+path, nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, r)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "spxgo_session" {
+			return w, c
+		}
+	}
+	return w, nil
+}
+
+func TestMiddlewareIssuesCookie(t *testing.T) {
+	store := session.NewMemoryStore()
+	e := newTestEngine(store)
+	// 没有使用会话或者只读取时不下发cookie
+	for _, path := range []string{"/ping", "/get"} {
+		if _, cookie := do(e, path, nil); cookie != nil {
+			t.Fatalf("%s issued cookie: %+v", path, cookie)
+		}
+	}
+	_, cookie := do(e, "/set", nil)
+	if cookie == nil || len(cookie.Value) != 64 || !cookie.HttpOnly {
+		t.Fatalf("unexpected cookie: %+v", cookie)
+	}
+	// cookie 的有效期和绝对过期时间一致
+	if cookie.MaxAge <= 0 || time.Duration(cookie.MaxAge)*time.Second > 24*time.Hour {
+		t.Fatalf("Max-Age = %d", cookie.MaxAge)
+	}
+	// 已有的会话不再下发cookie
+	if _, again := do(e, "/get", cookie); again != nil {
+		t.Fatalf("cookie issued again: %+v", again)
+	}
+	// 客户端伪造的id不会被使用
+	_, fresh := do(e, "/set", &http.Cookie{Name: "spxgo_session", Value: "../../etc/passwd"})
+	if fresh == nil || fresh.Value == "../../etc/passwd" {
+		t.Fatalf("invalid id was accepted: %+v", fresh)
+	}
+}
+
+func TestMiddlewareSaves(t *testing.T) {
+	store := session.NewMemoryStore()
+	e := newTestEngine(store)
+	_, cookie := do(e, "/set", nil)
+	if cookie == nil {
+		t.Fatal("no session cookie")
+	}
+	if _, ok, _ := store.Load(cookie.Value); !ok {
+		t.Fatal("session was not saved")
+	}
+	if w, _ := do(e, "/get", cookie); w.Body.String() != "bob" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	// 其它会话看不到
+	if w, _ := do(e, "/get", nil); w.Body.String() != "<nil>" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	store := session.NewMemoryStore()
+	e := newTestEngine(store)
+	_, cookie := do(e, "/set", nil)
+	_, renewed := do(e, "/login", cookie)
+	if renewed == nil || renewed.Value == cookie.Value {
+		t.Fatalf("id was not regenerated: %+v", renewed)
+	}
+	// 旧id失效 数据保留在新id下
+	if _, ok, _ := store.Load(cookie.Value); ok {
+		t.Fatal("old session still exists")
+	}
+	if w, _ := do(e, "/get", renewed); w.Body.String() != "bob" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	store := session.NewMemoryStore()
+	e := newTestEngine(store)
+	_, cookie := do(e, "/set", nil)
+	_, cleared := do(e, "/logout", cookie)
+	if cleared == nil || cleared.Value != "" || cleared.MaxAge >= 0 {
+		t.Fatalf("cookie was not cleared: %+v", cleared)
+	}
+	if _, ok, _ := store.Load(cookie.Value); ok {
+		t.Fatal("destroyed session was saved again")
+	}
+	w, fresh := do(e, "/get", cookie)
+	if w.Body.String() != "<nil>" || fresh != nil {
+		t.Fatalf("destroyed session was reused: body=%q cookie=%+v", w.Body.String(), fresh)
+	}
+}
+
+// countStore 记录保存的次数 可以让读写失败
+type countStore struct {
+	session.Store
+	saves   int
+	loadErr error
+	saveErr error
+}
+
+func (s *countStore) Load(id string) ([]byte, bool, error) {
+	if s.loadErr != nil {
+		return nil, false, s.loadErr
+	}
+	return s.Store.Load(id)
+}
+
+func (s *countStore) Save(id string, data []byte, expiresAt time.Time) error {
+	s.saves++
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	return s.Store.Save(id, data, expiresAt)
+}
+
+func TestMiddlewareUnusedSession(t *testing.T) {
+	store := &countStore{Store: session.NewMemoryStore()}
+	e := newTestEngine(store)
+	// 匿名请求不占用存储
+	for i := 0; i < 3; i++ {
+		do(e, "/ping", nil)
+		do(e, "/get", nil)
+	}
+	if store.saves != 0 {
+		t.Fatalf("saves = %d", store.saves)
+	}
+	_, cookie := do(e, "/set", nil)
+	if store.saves != 1 {
+		t.Fatalf("saves = %d", store.saves)
+	}
+	// 已有的会话没有使用时不保存 读取时保存以刷新空闲时间
+	do(e, "/ping", cookie)
+	if store.saves != 1 {
+		t.Fatalf("saves = %d", store.saves)
+	}
+	do(e, "/get", cookie)
+	if store.saves != 2 {
+		t.Fatalf("saves = %d", store.saves)
+	}
+}
+
+func TestMiddlewareStoreError(t *testing.T) {
+	// New 没有设置 Logger 存储出错时交给 ErrorHandler
+	store := &countStore{Store: session.NewMemoryStore()}
+	e := newTestEngine(store)
+	_, cookie := do(e, "/set", nil)
+	store.loadErr = errors.New("store down")
+	w, _ := do(e, "/get", cookie)
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") ||
+		strings.Contains(w.Body.String(), "store down") {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+	// 保存时响应已经写出 只记录错误
+	store.loadErr, store.saveErr = nil, errors.New("store down")
+	if w, _ := do(e, "/set", nil); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,59 @@
+// Package sessiontest 提供 session.Store 实现需要通过的通用用例
+package sessiontest
+
+import (
+	"bytes"
+	"gitbuh.com/spxzx/spxgo/session"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStore 所有 Store 实现都要通过的用例 store 应当是空的
+func TestStore(t *testing.T, store session.Store) {
+	t.Helper()
+	id := strings.Repeat("ab", 32)
+	other := strings.Repeat("cd", 32)
+
+	if _, ok, err := store.Load(id); err != nil || ok {
+		t.Fatalf("load missing session: ok=%v err=%v", ok, err)
+	}
+	if err := store.Save(id, []byte("first"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	data, ok, err := store.Load(id)
+	if err != nil || !ok || !bytes.Equal(data, []byte("first")) {
+		t.Fatalf("load saved session: data=%q ok=%v err=%v", data, ok, err)
+	}
+	// 覆盖写入
+	if err = store.Save(id, []byte("second"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, _ = store.Load(id); !bytes.Equal(data, []byte("second")) {
+		t.Fatalf("overwrite session: got %q", data)
+	}
+	// 会话之间互不影响
+	if err = store.Save(other, []byte("other"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ = store.Load(id); ok {
+		t.Fatal("deleted session still exists")
+	}
+	if data, ok, _ = store.Load(other); !ok || !bytes.Equal(data, []byte("other")) {
+		t.Fatalf("delete removed another session: data=%q ok=%v", data, ok)
+	}
+	// 删除不存在的会话不是错误
+	if err = store.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	// 过期
+	if err = store.Save(id, []byte("expired"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err = store.Load(id); err != nil || ok {
+		t.Fatalf("load expired session: ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"fmt"
+	"gitbuh.com/spxzx/spxgo/orm"
+	"time"
+)
+
+// sessionRow 会话表的一行 表结构见 SQLStore.CreateTable
+type sessionRow struct {
+	Id        string `spxorm:"id"`
+	Data      []byte `spxorm:"data"`
+	ExpiresAt int64  `spxorm:"expires_at"`
+}
+
+// SQLStore 通过 orm.SpxDb 存储会话
+type SQLStore struct {
+	db    *orm.SpxDb
+	table string
+}
+
+func NewSQLStore(db *orm.SpxDb, table string) *SQLStore {
+	if table == "" {
+		table = "spx_session"
+	}
+	return &SQLStore{db: db, table: table}
+}
+
+// CreateTable 创建会话表 已存在时不做任何操作
+func (s *SQLStore) CreateTable() error {
+	_, err := s.db.New(s.table).Exec(fmt.Sprintf(
+		"create table if not exists %s (id varchar(64) primary key, data blob, expires_at bigint not null)", s.table))
+	return err
+}
+
+func (s *SQLStore) Load(id string) ([]byte, bool, error) {
+	row := &sessionRow{}
+	if err := s.db.New(s.table).Where("id", id).SelectOne(row); err != nil {
+		return nil, false, err
+	}
+	if row.Id == "" {
+		return nil, false, nil
+	}
+	if time.Now().UnixNano() >= row.ExpiresAt {
+		return nil, false, s.Delete(id)
+	}
+	return row.Data, true, nil
+}
+
+func (s *SQLStore) Save(id string, data []byte, expiresAt time.Time) error {
+	row := &sessionRow{Id: id, Data: data, ExpiresAt: expiresAt.UnixNano()}
+	_, affected, err := s.db.New(s.table).Where("id", id).Update(row)
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	_, _, err = s.db.New(s.table).Insert(row)
+	return err
+}
+
+func (s *SQLStore) Delete(id string) error {
+	_, err := s.db.New(s.table).Where("id", id).Delete()
+	return err
+}
+
+// GC 删除过期的会话
+func (s *SQLStore) GC() error {
+	_, err := s.db.New(s.table).Exec(
+		fmt.Sprintf("delete from %s where expires_at <= ?", s.table), time.Now().UnixNano())
+	return err
+}
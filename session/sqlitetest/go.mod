@@ -0,0 +1,26 @@
+module gitbuh.com/spxzx/spxgo/session/sqlitetest
+
+go 1.19
+
+require (
+	gitbuh.com/spxzx/spxgo v0.0.0
+	github.com/mattn/go-sqlite3 v1.14.16
+)
+
+require (
+	github.com/BurntSushi/toml v1.2.0 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/go-playground/locales v0.14.0 // indirect
+	github.com/go-playground/universal-translator v0.18.0 // indirect
+	github.com/go-playground/validator/v10 v10.11.0 // indirect
+	github.com/leodido/go-urn v1.2.1 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/crypto v0.0.0-20211215153901-e495a2d5b3d3 // indirect
+	golang.org/x/sys v0.0.0-20210806184541-e5e7981a1069 // indirect
+	golang.org/x/text v0.3.7 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace gitbuh.com/spxzx/spxgo => ../..
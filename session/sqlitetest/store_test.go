@@ -0,0 +1,22 @@
+// Package sqlitetest 使用 sqlite 测试 session.SQLStore
+// 单独作为一个模块 避免 cgo 的 sqlite 驱动成为 spxgo 的依赖
+package sqlitetest
+
+import (
+	"gitbuh.com/spxzx/spxgo/orm"
+	"gitbuh.com/spxzx/spxgo/session"
+	"gitbuh.com/spxzx/spxgo/session/sessiontest"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLStore(t *testing.T) {
+	db := orm.Open("sqlite3", filepath.Join(t.TempDir(), "session.db"))
+	defer db.Close()
+	store := session.NewSQLStore(db, "")
+	if err := store.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	sessiontest.TestStore(t, store)
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// Store 会话数据的存储 data 是编码后的会话内容
+// 过期的会话 Load 时应当当作不存在
+type Store interface {
+	Load(id string) (data []byte, ok bool, err error)
+	Save(id string, data []byte, expiresAt time.Time) error
+	Delete(id string) error
+}
+
+type memoryItem struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryStore 内存存储 进程重启后会话丢失 适合单机和测试
+type MemoryStore struct {
+	mutex sync.RWMutex
+	items map[string]memoryItem
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+func (m *MemoryStore) Load(id string) ([]byte, bool, error) {
+	m.mutex.RLock()
+	item, ok := m.items[id]
+	m.mutex.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	if !time.Now().Before(item.expiresAt) {
+		return nil, false, m.Delete(id)
+	}
+	return item.data, true, nil
+}
+
+func (m *MemoryStore) Save(id string, data []byte, expiresAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.items[id] = memoryItem{data: append([]byte(nil), data...), expiresAt: expiresAt}
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.items, id)
+	return nil
+}
+
+// GC 清理过期的会话 可以定时调用
+func (m *MemoryStore) GC() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	for id, item := range m.items {
+		if !now.Before(item.expiresAt) {
+			delete(m.items, id)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package session_test
+
+import (
+	"gitbuh.com/spxzx/spxgo/session"
+	"gitbuh.com/spxzx/spxgo/session/sessiontest"
+	"testing"
+)
+
+// SQLStore 的用例依赖 cgo 的 sqlite 驱动 放在单独的模块 session/sqlitetest 中
+
+func TestMemoryStore(t *testing.T) {
+	sessiontest.TestStore(t, session.NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := session.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessiontest.TestStore(t, store)
+	if _, _, err = store.Load("../../etc/passwd"); err != session.ErrInvalidID {
+		t.Fatalf("path traversal id: err=%v", err)
+	}
+}
@@ -1,13 +1,20 @@
 package spxgo
 
 import (
+	"context"
 	"fmt"
+	"gitbuh.com/spxzx/spxgo/binding"
 	"gitbuh.com/spxzx/spxgo/config"
 	spxLog "gitbuh.com/spxzx/spxgo/log"
 	"gitbuh.com/spxzx/spxgo/render"
+	"gitbuh.com/spxzx/spxgo/serror"
+	"gitbuh.com/spxzx/spxgo/websocket"
 	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -40,6 +47,12 @@ func (r *routerGroup) Use(middlewareFunc ...MiddlewareFunc) {
 
 // 中间件的处理
 func (r *routerGroup) methodHandle(name string, method string, handleFunc HandlerFunc, c *Context) {
+	// 处理函数记录的错误在中间件之内生成响应 这样日志 压缩等中间件也能看到
+	handler := handleFunc
+	handleFunc = func(c *Context) {
+		handler(c)
+		c.handleErrors()
+	}
 	// 组通用中间件 preMiddlewares ** 废弃 已改成下面代码
 	if r.middlewares != nil {
 		for _, middlewareFunc := range r.middlewares {
@@ -53,6 +66,8 @@ func (r *routerGroup) methodHandle(name string, method string, handleFunc Handle
 		}
 	}
 	handleFunc(c) // 真正执行   pre -> handle() <- post
+	// 中间件在 next 之后记录的错误
+	c.handleErrors()
 	// postMiddlewares ** 废弃
 }
 
@@ -116,14 +131,47 @@ func (r *routerGroup) Head(name string, handlerFunc HandlerFunc, middlewareFunc
 	r.handle(name, http.MethodHead, handlerFunc, middlewareFunc...)
 }
 
+// WebSocket 注册 websocket 路由，组中间件和路由中间件(比如认证)都在握手之前执行
+// handlerFunc 中通过 c.WebSocket 收发消息，返回后连接会被关闭
+func (r *routerGroup) WebSocket(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
+	r.handle(name, http.MethodGet, func(c *Context) {
+		upgrader := c.engine.WebSocketUpgrader
+		if upgrader == nil {
+			upgrader = &websocket.Upgrader{}
+		}
+		conn, err := upgrader.Upgrade(c.W, c.R, nil)
+		if err != nil {
+			c.logError(err)
+			return
+		}
+		c.StatusCode = http.StatusSwitchingProtocols
+		c.WebSocket = conn
+		defer func() {
+			_ = conn.Close(websocket.CloseNormalClosure, "")
+		}()
+		handlerFunc(c)
+	}, middlewareFunc...)
+}
+
 type Engine struct {
 	router
-	funcMap      template.FuncMap // template.FuncMap 存疑
-	HTMLRender   render.HTML
+	funcMap    template.FuncMap // template.FuncMap 存疑
+	HTMLRender render.HTML      // SetHTMLRender LoadTemplate LoadTemplateFS 加载的模板 所有页面共用
+	// HTMLRenderer 不为空时 Context.Template 使用它而不是 HTMLRender 多页面布局使用 render.Templates
+	HTMLRenderer render.HTMLRender
+	delims       render.Delims
 	pool         sync.Pool      // sync.Pool 用于存储那些被分配了但是还没有被使用，但是未来可能使用的值，这样可以不用再次分配内存，提高效率
 	Logger       *spxLog.Logger // 分级日志
 	middles      []MiddlewareFunc
 	errorHandler ErrorHandler
+	// WebSocketUpgrader websocket 握手的配置 为空时使用默认配置(只允许同源)
+	WebSocketUpgrader *websocket.Upgrader
+	// CookieSecrets 签名和加密cookie使用的密钥 第一个用于写入 全部用于校验 轮换时把新密钥放到最前面
+	CookieSecrets [][]byte
+	// MaxBodySize 请求体的最大字节数 0 不限制 超出时绑定返回413 单个路由可以用 MaxBodySize 中间件另外设置
+	MaxBodySize int64
+	// MaxMultipartMemory 解析multipart表单时放在内存中的最大字节数 超出部分写入临时文件 0 使用32M
+	MaxMultipartMemory int64
 }
 
 func (e *Engine) allocateContext() any {
@@ -135,6 +183,7 @@ func New() *Engine {
 		// router: router{handleFuncMap: make(map[string]HandleFunc)},
 		router: router{},
 	}
+	engine.router.engine = engine // 使能够使用中间件
 	engine.pool.New = func() any {
 		return engine.allocateContext()
 	}
@@ -153,23 +202,32 @@ func Default() *Engine {
 		engine.Logger.SetLogPath(logPath.(string))
 	}
 	engine.Use(Logging, Recovery)
-	engine.router.engine = engine // 使能够使用中间件
 	return engine
 }
 
-// SetFuncMap
-// Deprecated
+// SetFuncMap LoadTemplate 和 LoadTemplateFS 使用的模板函数 需要在加载模板之前调用
 func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
 	e.funcMap = funcMap // 设置 map[string]any 映射键值对
 }
 
+// Delims LoadTemplate 和 LoadTemplateFS 使用的分隔符 需要在加载模板之前调用
+func (e *Engine) Delims(left, right string) {
+	e.delims = render.Delims{Left: left, Right: right}
+}
+
 func (e *Engine) SetHTMLRender(t *template.Template) {
 	e.HTMLRender = render.HTML{Template: t}
 }
 
 func (e *Engine) LoadTemplate(pattern string) {
 	// New() 创建一个名为name的模板 Funcs() 模板template的函数字典里加入参数funcMap内的键值对
-	t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
+	t := template.Must(template.New("").Delims(e.delims.Left, e.delims.Right).Funcs(e.funcMap).ParseGlob(pattern))
+	e.SetHTMLRender(t)
+}
+
+// LoadTemplateFS 从 fs.FS(比如 embed.FS) 中加载模板 所有页面共用一个模板
+func (e *Engine) LoadTemplateFS(fsys fs.FS, patterns ...string) {
+	t := template.Must(template.New("").Delims(e.delims.Left, e.delims.Right).Funcs(e.funcMap).ParseFS(fsys, patterns...))
 	e.SetHTMLRender(t)
 }
 
@@ -178,39 +236,55 @@ func (e *Engine) httpRequestHandle(c *Context, w http.ResponseWriter, r *http.Re
 	method := r.Method
 	for _, group := range e.routerGroups {
 		// URL不能使用r.RequestURI,这个会包含传来的参数
-		routerName := subStringLast(r.URL.Path, "/"+group.name)
-		// routerName /get/1   mode /get/:id
-		node := group.treeNode.Get(routerName)
+		path := subStringLast(r.URL.Path, "/"+group.name)
+		// path /get/1   routerName /get/:id
+		node, routerName := group.treeNode.Get(path)
 		if node != nil && node.isLeaf {
 			// 路由匹配成功
-			if handlerFunc, ok := group.handlerFuncMap[node.routerName][MethodAny]; ok {
-				group.methodHandle(node.routerName, MethodAny, handlerFunc, c)
+			if params := routerParams(routerName, path); len(params) > 0 {
+				c.params = params
+				c.R = binding.WithParams(c.R, params)
+			}
+			if handlerFunc, ok := group.handlerFuncMap[routerName][MethodAny]; ok {
+				group.methodHandle(routerName, MethodAny, handlerFunc, c)
 				return
 			}
-			if handlerFunc, ok := group.handlerFuncMap[node.routerName][method]; ok {
-				group.methodHandle(node.routerName, method, handlerFunc, c)
+			if handlerFunc, ok := group.handlerFuncMap[routerName][method]; ok {
+				group.methodHandle(routerName, method, handlerFunc, c)
 				return
 			}
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			_, _ = fmt.Fprintf(w, "%s %s not allowed \n", r.RequestURI, method)
+			// 405 需要通过 Allow 告诉客户端支持的请求方法
+			methods := make([]string, 0, len(group.handlerFuncMap[routerName]))
+			for m := range group.handlerFuncMap[routerName] {
+				methods = append(methods, m)
+			}
+			sort.Strings(methods)
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			_ = c.Problem(serror.NewProblem(http.StatusMethodNotAllowed, fmt.Sprintf("%s %s not allowed", method, r.URL.Path)))
 			return
 		}
 	}
-	w.WriteHeader(http.StatusNotFound)
-	_, _ = fmt.Fprintf(w, "%s not found \n", r.RequestURI)
+	_ = c.Problem(serror.NewProblem(http.StatusNotFound, fmt.Sprintf("%s not found", r.URL.Path)))
 }
 
 // 实现http下的接口ServeHTTP
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// pool -> 为了解决频繁创建Context的问题
 	c := e.pool.Get().(*Context)
-	c.W = w
-	c.R = r
+	c.reset()
+	c.writer.reset(w)
+	c.W = &c.writer
+	// 放入请求的 Context 中 校验规则等只拿到 context.Context 的地方可以用 FromContext 取回
+	c.R = r.WithContext(context.WithValue(r.Context(), contextKey{}, c))
+	c.body = r.Body
+	c.SetMaxBodySize(e.MaxBodySize)
 	c.Logger = e.Logger
 	e.httpRequestHandle(c, w, r)
 	e.pool.Put(c)
 }
 
+// RegisterErrorHandler 设置 Context.Error 和 HandlerFuncE 返回的错误生成的状态码和json内容
+// 不设置时返回 serror.Problem 校验错误422 其它错误500
 func (e *Engine) RegisterErrorHandler(handler ErrorHandler) {
 	e.errorHandler = handler
 }
@@ -0,0 +1,97 @@
+package spxgo
+
+import (
+	"gitbuh.com/spxzx/spxgo/render"
+	"net/http"
+	"time"
+)
+
+const defaultSSEHeartbeat = 15 * time.Second
+
+// SSEConfig 事件流的配置
+type SSEConfig struct {
+	Heartbeat time.Duration // 心跳注释的间隔 默认15s 小于0时关闭
+	// OnResume 客户端带着 Last-Event-ID 重连时调用，返回需要补发的事件
+	OnResume func(c *Context, lastEventID string) []render.SSE
+}
+
+// ClientGone 客户端是否已经断开连接，长时间运行的处理函数可以据此停止写入
+func (c *Context) ClientGone() bool {
+	return c.R.Context().Err() != nil
+}
+
+// LastEventID 浏览器重连时会带上最后收到的事件id，不支持自定义header的客户端可以用 lastEventId 参数
+func (c *Context) LastEventID() string {
+	if id := c.R.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.GetQuery("lastEventId")
+}
+
+// SSEvent 发送一条事件并立即刷新
+func (c *Context) SSEvent(name string, data any) error {
+	return c.sseEvent(&render.SSE{Event: name, Data: data})
+}
+
+func (c *Context) sseEvent(event *render.SSE) error {
+	if err := c.R.Context().Err(); err != nil {
+		return err
+	}
+	if err := c.Render(http.StatusOK, event); err != nil {
+		return err
+	}
+	c.flush()
+	return nil
+}
+
+func (c *Context) flush() {
+	if flusher, ok := c.W.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// SSEStream 把 events 中的事件持续推送给客户端，定时发送心跳注释
+// events 关闭时返回 false，客户端断开时返回 true，生产者应通过 ClientGone 或 c.R.Context() 停止
+func (c *Context) SSEStream(conf SSEConfig, events <-chan render.SSE) bool {
+	heartbeat := conf.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultSSEHeartbeat
+	}
+	(&render.SSE{}).WriteContentType(c.W)
+	c.W.WriteHeader(http.StatusOK)
+	c.StatusCode = http.StatusOK
+	c.flush()
+	if lastEventID := c.LastEventID(); lastEventID != "" && conf.OnResume != nil {
+		for _, event := range conf.OnResume(c, lastEventID) {
+			event := event
+			if err := c.sseEvent(&event); err != nil {
+				return true
+			}
+		}
+	}
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case <-tick:
+			if err := render.WriteSSEComment(c.W, "ping"); err != nil {
+				return true
+			}
+			c.flush()
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.sseEvent(&event); err != nil {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package spxgo
+
+import (
+	"context"
+	"gitbuh.com/spxzx/spxgo/render"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEStream(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+	c := newTestContext(w, r)
+	events := make(chan render.SSE)
+	go func() {
+		events <- render.SSE{Id: "3", Data: "three"}
+		// 等待至少一次心跳
+		time.Sleep(50 * time.Millisecond)
+		close(events)
+	}()
+	gone := c.SSEStream(SSEConfig{
+		Heartbeat: 10 * time.Millisecond,
+		OnResume: func(c *Context, lastEventID string) []render.SSE {
+			return []render.SSE{{Id: "2", Data: "resumed after " + lastEventID}}
+		},
+	}, events)
+	if gone {
+		t.Fatal("closing the channel should not report the client as gone")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body := w.Body.String()
+	resumed := strings.Index(body, "id: 2\ndata: resumed after 1\n\n")
+	event := strings.Index(body, "id: 3\ndata: three\n\n")
+	if resumed < 0 || event < resumed {
+		t.Fatalf("events missing or out of order: %q", body)
+	}
+	if !strings.Contains(body, ": ping\n\n") {
+		t.Fatalf("no heartbeat: %q", body)
+	}
+}
+
+func TestSSEStreamClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	c := newTestContext(httptest.NewRecorder(), r)
+	cancel()
+	if !c.SSEStream(SSEConfig{Heartbeat: -1}, make(chan render.SSE)) {
+		t.Fatal("cancelled request should report the client as gone")
+	}
+	if !c.ClientGone() {
+		t.Fatal("ClientGone = false")
+	}
+}
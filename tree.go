@@ -4,10 +4,9 @@ import "strings"
 
 // 前缀树
 type treeNode struct {
-	name       string
-	routerName string
-	isLeaf     bool
-	children   []*treeNode
+	name     string
+	isLeaf   bool
+	children []*treeNode
 }
 
 // Put path: /get/:id ... - > " " "get" ":id"
@@ -45,8 +44,9 @@ func (t *treeNode) Put(path string) {
 	}
 }
 
-// Get path: /any/*/get ...
-func (t *treeNode) Get(path string) *treeNode {
+// Get path: /any/*/get ... 同时返回匹配到的路由 /any/*/get
+// 并发的请求共用同一棵树 匹配过程中不能修改结点
+func (t *treeNode) Get(path string) (*treeNode, string) {
 	temp, strs, routerName := t, strings.Split(path, "/"), ""
 	for index, name := range strs {
 		if index == 0 {
@@ -64,11 +64,10 @@ func (t *treeNode) Get(path string) *treeNode {
 				strings.Contains(node.name, ":") {
 				isMatch = true
 				routerName += "/" + node.name
-				node.routerName = routerName
 				temp = node
 				// 到达最后一个结点，就将该结点返回
 				if index == len(strs)-1 {
-					return node
+					return node, routerName
 				}
 				break
 			}
@@ -80,12 +79,28 @@ func (t *treeNode) Get(path string) *treeNode {
 				// -> /usr/get
 				// -> /usr/get/info
 				if node.name == "**" {
-					routerName += "/" + node.name
-					node.routerName = routerName
-					return node
+					return node, routerName + "/" + node.name
 				}
 			}
 		}
 	}
-	return nil
+	return nil, ""
+}
+
+// routerParams 根据匹配到的路由 /get/:id 取出实际路径 /get/1 中的参数 {"id": "1"}
+func routerParams(routerName, path string) map[string]string {
+	var params map[string]string
+	names, values := strings.Split(routerName, "/"), strings.Split(path, "/")
+	for index, name := range names {
+		if index >= len(values) {
+			break
+		}
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[name[i+1:]] = values[index]
+		}
+	}
+	return params
 }
@@ -0,0 +1,285 @@
+package spxgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const sniffLen = 512 // http.DetectContentType 最多只看前 512 个字节
+
+var (
+	ErrUploadTooLarge       = errors.New("upload file too large")
+	ErrUploadTotalTooLarge  = errors.New("upload request too large")
+	ErrUploadTypeNotAllowed = errors.New("upload file type not allowed")
+)
+
+// UploadStore 上传文件的存储后端
+type UploadStore interface {
+	// Save 保存文件内容，name 已经过清洗，返回文件在存储中的标识
+	Save(name string, r io.Reader) (string, error)
+	// Delete 删除 Save 返回的文件，用于上传中途失败时的清理
+	Delete(key string) error
+}
+
+// UploadConfig 流式上传的限制
+type UploadConfig struct {
+	MaxFileSize  int64    // 单个文件最大字节数 0 不限制
+	MaxTotalSize int64    // 整个请求中文件与表单的总字节数 0 不限制
+	AllowedTypes []string // 允许的 MIME 类型 支持 image/* 这种写法 为空不限制
+	Store        UploadStore
+}
+
+// UploadFile 已经保存的文件信息
+type UploadFile struct {
+	FieldName   string
+	Filename    string // 清洗后的文件名
+	ContentType string // 嗅探出的类型，不信任客户端传来的 Content-Type
+	Size        int64
+	Key         string // UploadStore 返回的标识
+}
+
+// UploadPart 流式读取中的一个表单项
+type UploadPart struct {
+	FieldName   string
+	Filename    string
+	ContentType string
+	reader      io.Reader
+	size        int64
+	ur          *UploadReader
+}
+
+// IsFile 是否是文件，普通的表单字段没有文件名
+func (p *UploadPart) IsFile() bool {
+	return p.Filename != ""
+}
+
+// Size 目前为止已经读取的字节数
+func (p *UploadPart) Size() int64 {
+	return p.size
+}
+
+func (p *UploadPart) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.size += int64(n)
+	p.ur.total += int64(n)
+	if p.ur.conf.MaxFileSize > 0 && p.IsFile() && p.size > p.ur.conf.MaxFileSize {
+		return n, ErrUploadTooLarge
+	}
+	if p.ur.conf.MaxTotalSize > 0 && p.ur.total > p.ur.conf.MaxTotalSize {
+		return n, ErrUploadTotalTooLarge
+	}
+	return n, err
+}
+
+// UploadReader 不经过 ParseMultipartForm 直接读取请求体，文件不会落到临时目录
+type UploadReader struct {
+	reader *multipart.Reader
+	conf   UploadConfig
+	total  int64
+}
+
+// UploadReader 返回请求的流式 multipart 读取器
+func (c *Context) UploadReader(conf UploadConfig) (*UploadReader, error) {
+	reader, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return &UploadReader{reader: reader, conf: conf}, nil
+}
+
+// Next 返回下一个表单项，没有时返回 io.EOF
+// 文件类型会在这里根据内容嗅探并校验
+func (u *UploadReader) Next() (*UploadPart, error) {
+	part, err := u.reader.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	p := &UploadPart{
+		FieldName: part.FormName(),
+		Filename:  part.FileName(),
+		reader:    part,
+		ur:        u,
+	}
+	if !p.IsFile() {
+		return p, nil
+	}
+	p.Filename = SanitizeFilename(p.Filename)
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+	p.ContentType = http.DetectContentType(head)
+	if !allowedType(u.conf.AllowedTypes, p.ContentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUploadTypeNotAllowed, p.ContentType)
+	}
+	p.reader = io.MultiReader(bytes.NewReader(head), part)
+	return p, nil
+}
+
+// SaveUploads 读取整个请求，文件写入 conf.Store，普通字段放到返回的 url.Values 中
+// 任何一个文件失败都会删除本次已经保存的文件
+func (c *Context) SaveUploads(conf UploadConfig) ([]*UploadFile, url.Values, error) {
+	if conf.Store == nil {
+		return nil, nil, errors.New("upload store is nil")
+	}
+	reader, err := c.UploadReader(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make([]*UploadFile, 0)
+	values := url.Values{}
+	clean := func() {
+		for _, f := range files {
+			if err := conf.Store.Delete(f.Key); err != nil {
+				c.logError(err)
+			}
+		}
+	}
+	for {
+		part, err := reader.Next()
+		if err == io.EOF {
+			return files, values, nil
+		}
+		if err != nil {
+			clean()
+			return nil, nil, err
+		}
+		if !part.IsFile() {
+			var sb strings.Builder
+			if _, err := io.Copy(&sb, part); err != nil {
+				clean()
+				return nil, nil, err
+			}
+			values.Add(part.FieldName, sb.String())
+			continue
+		}
+		key, err := conf.Store.Save(part.Filename, part)
+		if err != nil {
+			if key != "" {
+				_ = conf.Store.Delete(key)
+			}
+			clean()
+			return nil, nil, err
+		}
+		files = append(files, &UploadFile{
+			FieldName:   part.FieldName,
+			Filename:    part.Filename,
+			ContentType: part.ContentType,
+			Size:        part.Size(),
+			Key:         key,
+		})
+	}
+}
+
+// SaveUploadFileTo 把 ParseMultipartForm 解析出的文件保存到 store 文件名经过清洗 返回 store 中的标识
+func (c *Context) SaveUploadFileTo(file *multipart.FileHeader, store UploadStore) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	key, err := store.Save(SanitizeFilename(file.Filename), src)
+	if err != nil {
+		if key != "" {
+			_ = store.Delete(key)
+		}
+		return "", err
+	}
+	return key, nil
+}
+
+func allowedType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	// 去掉 ; charset=utf-8 这类参数
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(strings.ToLower(contentType))
+	for _, t := range allowed {
+		t = strings.ToLower(t)
+		if t == contentType || t == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeFilename 只保留文件名部分，防止 ../../etc/passwd 这类路径穿越
+func SanitizeFilename(name string) string {
+	// windows 客户端可能传来 C:\Users\a.txt
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base("/" + name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '/' || r == ':' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// LocalStore 本地磁盘存储
+type LocalStore struct {
+	Root string
+	Perm os.FileMode // 默认 0644
+}
+
+// Save 文件已存在时会加上 -1 -2 这样的后缀，不会覆盖已有文件
+func (s *LocalStore) Save(name string, r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.Root, 0755); err != nil {
+		return "", err
+	}
+	perm := s.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	name = SanitizeFilename(name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	var out *os.File
+	var dst string
+	for i := 0; ; i++ {
+		dst = filepath.Join(s.Root, name)
+		if i > 0 {
+			dst = filepath.Join(s.Root, fmt.Sprintf("%s-%d%s", base, i, ext))
+		}
+		f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if err == nil {
+			out = f
+			break
+		}
+		if !errors.Is(err, os.ErrExist) || i >= 1000 {
+			return "", err
+		}
+	}
+	key := filepath.Base(dst)
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return key, err
+	}
+	return key, out.Close()
+}
+
+func (s *LocalStore) Delete(key string) error {
+	return os.Remove(filepath.Join(s.Root, SanitizeFilename(key)))
+}
@@ -0,0 +1,219 @@
+package spxgo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestContext 不经过路由直接构造 Context
+func newTestContext(w http.ResponseWriter, r *http.Request) *Context {
+	c := &Context{engine: New()}
+	c.reset()
+	c.writer.reset(w)
+	c.W = &c.writer
+	c.R = r
+	c.body = r.Body
+	return c
+}
+
+type testPart struct {
+	field, filename string
+	content         []byte
+}
+
+func multipartRequest(t *testing.T, parts ...testPart) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var err error
+		if p.filename == "" {
+			err = mw.WriteField(p.field, string(p.content))
+		} else {
+			var fw io.Writer
+			fw, err = mw.CreateFormFile(p.field, p.filename)
+			if err == nil {
+				_, err = fw.Write(p.content)
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := map[string]string{
+		"a.txt":               "a.txt",
+		"../../etc/passwd":    "passwd",
+		`C:\Users\me\a.txt`:   "a.txt",
+		"..":                  "file",
+		"/":                   "file",
+		".hidden":             "hidden",
+		"a\x00b\nc.txt":       "abc.txt",
+		"dir/sub/../name.png": "name.png",
+	}
+	for in, want := range cases {
+		if got := SanitizeFilename(in); got != want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAllowedType(t *testing.T) {
+	cases := []struct {
+		allowed     []string
+		contentType string
+		want        bool
+	}{
+		{nil, "application/octet-stream", true},
+		{[]string{"image/*"}, "image/png", true},
+		{[]string{"image/*"}, "text/plain; charset=utf-8", false},
+		{[]string{"text/plain"}, "text/plain; charset=utf-8", true},
+		{[]string{"*/*"}, "application/zip", true},
+	}
+	for _, tc := range cases {
+		if got := allowedType(tc.allowed, tc.contentType); got != tc.want {
+			t.Errorf("allowedType(%v, %q) = %v, want %v", tc.allowed, tc.contentType, got, tc.want)
+		}
+	}
+}
+
+func TestSaveUploads(t *testing.T) {
+	root := t.TempDir()
+	store := &LocalStore{Root: root}
+	r := multipartRequest(t,
+		testPart{field: "title", content: []byte("hello")},
+		testPart{field: "file", filename: "../../evil.png", content: pngHeader},
+	)
+	files, values, err := newTestContext(httptest.NewRecorder(), r).SaveUploads(UploadConfig{
+		AllowedTypes: []string{"image/*"},
+		Store:        store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.Get("title") != "hello" {
+		t.Fatalf("form value = %q", values.Get("title"))
+	}
+	if len(files) != 1 || files[0].Filename != "evil.png" || files[0].ContentType != "image/png" || files[0].Size != int64(len(pngHeader)) {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	if _, err = os.Stat(filepath.Join(root, files[0].Key)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveUploadsCleanUp(t *testing.T) {
+	cases := map[string]struct {
+		conf UploadConfig
+		want error
+	}{
+		"file too large":   {UploadConfig{MaxFileSize: 16}, ErrUploadTooLarge},
+		"total too large":  {UploadConfig{MaxTotalSize: 40}, ErrUploadTotalTooLarge},
+		"type not allowed": {UploadConfig{AllowedTypes: []string{"image/*"}}, ErrUploadTypeNotAllowed},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			tc.conf.Store = &LocalStore{Root: root}
+			// 第一个文件合法 第二个文件失败 第一个文件也要被删除
+			r := multipartRequest(t,
+				testPart{field: "a", filename: "a.png", content: pngHeader},
+				testPart{field: "b", filename: "b.txt", content: bytes.Repeat([]byte("text "), 10)},
+			)
+			_, _, err := newTestContext(httptest.NewRecorder(), r).SaveUploads(tc.conf)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			entries, err := os.ReadDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("saved files were not cleaned up: %d left", len(entries))
+			}
+		})
+	}
+}
+
+func TestSaveUploadFile(t *testing.T) {
+	root := t.TempDir()
+	r := multipartRequest(t,
+		testPart{field: "file", filename: "a.txt", content: []byte("data")},
+		testPart{field: "evil", filename: "..evil.txt", content: []byte("data")},
+	)
+	c := newTestContext(httptest.NewRecorder(), r)
+	form, err := c.MultipartFormFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := form.File["file"][0]
+	// dst 由服务端决定 原样使用
+	if err = c.SaveUploadFile(header, filepath.Join(root, ".env")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(root, ".env")); err != nil {
+		t.Fatal(err)
+	}
+	// 客户端传来的文件名会被清洗
+	if err = c.SaveAllUploadFiles(form.File["evil"], root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "evil.txt")); err != nil {
+		t.Fatal(err)
+	}
+	key, err := c.SaveUploadFileTo(header, &LocalStore{Root: root})
+	if err != nil || key != "a.txt" {
+		t.Fatalf("SaveUploadFileTo: key=%q err=%v", key, err)
+	}
+}
+
+func TestBindMultipartMemory(t *testing.T) {
+	type form struct {
+		Name string `form:"name"`
+	}
+	binds := map[string]func(c *Context, obj any) error{
+		"Bind":              (*Context).Bind,
+		"BindForm":          (*Context).BindForm,
+		"BindFormMultipart": (*Context).BindFormMultipart,
+	}
+	for name, bind := range binds {
+		for _, limit := range []int64{0, 16} {
+			r := multipartRequest(t, testPart{field: "name", content: []byte("bob")},
+				testPart{field: "avatar", filename: "a.png", content: bytes.Repeat([]byte("x"), 64)})
+			c := newTestContext(httptest.NewRecorder(), r)
+			c.engine.MaxMultipartMemory = limit
+			var f form
+			if err := bind(c, &f); err != nil || f.Name != "bob" || len(r.MultipartForm.File["avatar"]) != 1 {
+				t.Fatalf("%s: %+v %v", name, f, err)
+			}
+			file, err := r.MultipartForm.File["avatar"][0].Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 超出 MaxMultipartMemory 的文件写入临时文件
+			_, onDisk := file.(*os.File)
+			_ = file.Close()
+			if onDisk != (limit > 0) {
+				t.Errorf("%s MaxMultipartMemory=%d: file on disk = %v", name, limit, onDisk)
+			}
+			_ = r.MultipartForm.RemoveAll()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package spxgo
+
+import (
+	"gitbuh.com/spxzx/spxgo/binding"
+	"gitbuh.com/spxzx/spxgo/serror"
+	ut "github.com/go-playground/universal-translator"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Translator 根据请求头 Accept-Language 选择校验提示的语言 都不支持时使用英文
+func (c *Context) Translator() ut.Translator {
+	return binding.Translator(acceptLanguages(c.R.Header.Get("Accept-Language"))...)
+}
+
+// FailValidation 输出 application/problem+json 校验错误返回422 errors 中为 binding.FieldError 的列表
+// 请求体过大返回413 其它错误(比如json格式不对)返回400 detail 为错误信息
+func (c *Context) FailValidation(err error) error {
+	if problem, ok := validationProblem(err, c.Translator()); ok {
+		return c.Problem(problem)
+	}
+	return c.Problem(serror.NewProblem(http.StatusBadRequest, err.Error()))
+}
+
+// validationProblem 校验错误和请求体过大的错误转换成 Problem 其它错误返回 false
+func validationProblem(err error, trans ut.Translator) (*serror.Problem, bool) {
+	if ve, ok := binding.NewValidationError(err, trans); ok {
+		return serror.NewProblem(http.StatusUnprocessableEntity, "").With("errors", ve.Errors), true
+	}
+	if isBodyTooLarge(err) {
+		return serror.NewProblem(http.StatusRequestEntityTooLarge, "request body too large"), true
+	}
+	return nil, false
+}
+
+// acceptLanguages 按权重从高到低返回语言 zh-CN -> zh_CN 后面补上主语言 zh
+func acceptLanguages(header string) []string {
+	type language struct {
+		tag string
+		q   float64
+	}
+	var langs []language
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if f, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				q = f
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, language{tag: strings.ReplaceAll(tag, "-", "_"), q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	locales := make([]string, 0, len(langs)*2)
+	for _, l := range langs {
+		locales = append(locales, l.tag)
+		if base, _, ok := strings.Cut(l.tag, "_"); ok {
+			locales = append(locales, base)
+		}
+	}
+	return locales
+}
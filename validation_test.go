@@ -0,0 +1,141 @@
+package spxgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gitbuh.com/spxzx/spxgo/binding"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcceptLanguages(t *testing.T) {
+	got := acceptLanguages("en;q=0.5, zh-CN, fr;q=0, *")
+	want := []string{"zh_CN", "zh", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("acceptLanguages = %v, want %v", got, want)
+	}
+}
+
+func TestFailValidation(t *testing.T) {
+	type user struct {
+		Name string `json:"name" validate:"required"`
+	}
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+		fields []binding.FieldError
+	}{
+		{"validation", binding.Validator.ValidateStruct(&user{}), http.StatusUnprocessableEntity, "",
+			[]binding.FieldError{{Field: "name", Rule: "required", Message: "name为必填字段"}}},
+		{"too large", &http.MaxBytesError{Limit: 10}, http.StatusRequestEntityTooLarge, "request body too large", nil},
+		{"bad request", errors.New("unexpected EOF"), http.StatusBadRequest, "unexpected EOF", nil},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/users", nil)
+		r.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+		w := httptest.NewRecorder()
+		if err := newTestContext(w, r).FailValidation(tc.err); err != nil {
+			t.Fatal(err)
+		}
+		// 三种错误都使用 Problem 格式
+		if w.Code != tc.status || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+			t.Errorf("%s: status = %d Content-Type = %q", tc.name, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		var body struct {
+			Status   int                  `json:"status"`
+			Detail   string               `json:"detail"`
+			Instance string               `json:"instance"`
+			Errors   []binding.FieldError `json:"errors"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v: %s", tc.name, err, w.Body.String())
+		}
+		if body.Status != tc.status || body.Detail != tc.detail || body.Instance != "/users" || !reflect.DeepEqual(body.Errors, tc.fields) {
+			t.Errorf("%s: body = %s", tc.name, w.Body.String())
+		}
+	}
+}
+
+func TestBindValidationLanguage(t *testing.T) {
+	type user struct {
+		Name string `json:"name" validate:"required"`
+	}
+	e := newTestEngine()
+	g := e.Group("v")
+	g.Post("/bind", func(c *Context) {
+		var u user
+		_ = c.Bind(&u)
+	})
+	g.Post("/returned", HandleE(func(c *Context) error {
+		var u user
+		if err := c.ShouldBind(&u); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	}))
+	for _, path := range []string{"/v/bind", "/v/returned"} {
+		for lang, want := range map[string]string{"zh-CN,zh;q=0.9": "name为必填字段", "": "name is a required field"} {
+			w := serve(e, http.MethodPost, path, strings.NewReader(`{}`), map[string]string{
+				"Content-Type":    "application/json",
+				"Accept-Language": lang,
+			})
+			var body struct {
+				Errors []binding.FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("%s: %v: %s", path, err, w.Body.String())
+			}
+			if w.Code != http.StatusUnprocessableEntity || len(body.Errors) != 1 || body.Errors[0].Message != want {
+				t.Errorf("%s Accept-Language=%q: status = %d body = %s", path, lang, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestBindValidationCtxRule(t *testing.T) {
+	// 规则通过 FromContext 取到当前请求
+	err := binding.RegisterValidationCtx("spx_tenant", func(ctx context.Context, fl validator.FieldLevel) bool {
+		c, ok := FromContext(ctx)
+		return ok && fl.Field().String() == c.R.Header.Get("X-Tenant")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = binding.RegisterTranslation("en", "spx_tenant", "{0} does not belong to this tenant"); err != nil {
+		t.Fatal(err)
+	}
+	type order struct {
+		Tenant string `json:"tenant" validate:"spx_tenant"`
+	}
+	e := newTestEngine()
+	e.Group("v").Post("/orders", HandleE(func(c *Context) error {
+		var o order
+		if err := c.Bind(&o); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, o.Tenant)
+	}))
+	header := map[string]string{"Content-Type": "application/json", "X-Tenant": "acme"}
+	if w := serve(e, http.MethodPost, "/v/orders", strings.NewReader(`{"tenant":"acme"}`), header); w.Code != http.StatusOK || w.Body.String() != "acme" {
+		t.Fatalf("status = %d body = %q", w.Code, w.Body.String())
+	}
+	w := serve(e, http.MethodPost, "/v/orders", strings.NewReader(`{"tenant":"other"}`), header)
+	var body struct {
+		Errors []binding.FieldError `json:"errors"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%v: %s", err, w.Body.String())
+	}
+	want := []binding.FieldError{{Field: "tenant", Rule: "spx_tenant", Message: "tenant does not belong to this tenant"}}
+	if w.Code != http.StatusUnprocessableEntity || !reflect.DeepEqual(body.Errors, want) {
+		t.Fatalf("status = %d body = %s", w.Code, w.Body.String())
+	}
+}
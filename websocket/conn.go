@@ -0,0 +1,480 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型 与帧的 opcode 对应
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// 关闭码 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finalBit = 1 << 7
+	rsv1Bit  = 1 << 6
+	maskBit  = 1 << 7
+
+	maxControlPayload     = 125
+	defaultMaxMessageSize = 32 << 20 // 32M
+	defaultWriteTimeout   = 10 * time.Second
+)
+
+var (
+	ErrMessageTooBig = errors.New("websocket: message too big")
+	ErrClosed        = errors.New("websocket: use of closed connection")
+)
+
+// CloseError 对端发来的关闭帧
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+// IsCloseError 判断 err 是否是指定关闭码的 CloseError
+func IsCloseError(err error, codes ...int) bool {
+	var e *CloseError
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, code := range codes {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn 面向消息的 websocket 连接
+// 同一时间只能有一个协程读，写操作内部加了锁可以并发调用
+type Conn struct {
+	conn                   net.Conn
+	br                     *bufio.Reader
+	subprotocol            string
+	compress               bool  // 是否协商了 permessage-deflate
+	EnableWriteCompression bool  // 发送时是否压缩，协商成功后默认开启
+	MaxMessageSize         int64 // 单条消息最大字节数 小于等于0时使用32M
+	// WriteTimeout 没有通过 SetWriteDeadline 设置截止时间时 每次写入的超时 默认10s 小于0不限制
+	// 防止对端不再读取时写入一直阻塞
+	WriteTimeout time.Duration
+
+	writeMutex    sync.Mutex
+	closeOnce     sync.Once
+	closeSent     bool
+	deadlineMutex sync.Mutex
+	writeDeadline time.Time // SetWriteDeadline 设置的截止时间
+
+	PingHandler  func(data []byte) error // 默认回复 pong
+	PongHandler  func(data []byte) error
+	CloseHandler func(code int, reason string) error // 默认回复同样的关闭码
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, subprotocol string, compress bool) *Conn {
+	c := &Conn{
+		conn:                   conn,
+		br:                     br,
+		subprotocol:            subprotocol,
+		compress:               compress,
+		EnableWriteCompression: compress,
+		MaxMessageSize:         defaultMaxMessageSize,
+		WriteTimeout:           defaultWriteTimeout,
+	}
+	c.PingHandler = func(data []byte) error {
+		return c.WriteControl(PongMessage, data, time.Now().Add(time.Second))
+	}
+	c.PongHandler = func([]byte) error { return nil }
+	c.CloseHandler = func(code int, reason string) error {
+		if code == CloseNoStatusReceived {
+			code = CloseNormalClosure
+		}
+		return c.WriteControl(CloseMessage, FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+	}
+	return c
+}
+
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 之后的消息写入都使用该截止时间 零值表示恢复使用 WriteTimeout
+// 可以在其它协程中调用 让阻塞中的写入超时返回
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.deadlineMutex.Lock()
+	c.writeDeadline = t
+	c.deadlineMutex.Unlock()
+	return c.conn.SetWriteDeadline(t)
+}
+
+// deadline 本次写入的截止时间 优先使用调用方传入的 其次是 SetWriteDeadline 设置的 最后是 WriteTimeout
+func (c *Conn) deadline(deadline time.Time) time.Time {
+	if !deadline.IsZero() {
+		return deadline
+	}
+	c.deadlineMutex.Lock()
+	deadline = c.writeDeadline
+	c.deadlineMutex.Unlock()
+	if deadline.IsZero() && c.WriteTimeout > 0 {
+		deadline = time.Now().Add(c.WriteTimeout)
+	}
+	return deadline
+}
+
+func (c *Conn) maxMessageSize() int64 {
+	if c.MaxMessageSize <= 0 {
+		return defaultMaxMessageSize
+	}
+	return c.MaxMessageSize
+}
+
+// FormatCloseMessage 关闭帧的负载 2字节关闭码 + 原因
+func FormatCloseMessage(code int, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], reason)
+	return buf
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+func (c *Conn) readFrame(limit int64) (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return nil, err
+	}
+	f := &frame{
+		fin:    head[0]&finalBit != 0,
+		rsv1:   head[0]&rsv1Bit != 0,
+		opcode: int(head[0] & 0x0f),
+	}
+	if head[0]&0x30 != 0 {
+		return nil, c.protocolError("unexpected reserved bits")
+	}
+	if f.rsv1 && !c.compress {
+		return nil, c.protocolError("unexpected reserved bits")
+	}
+	// 客户端发来的帧必须带掩码
+	if head[1]&maskBit == 0 {
+		return nil, c.protocolError("client frame is not masked")
+	}
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+		if length < 0 {
+			return nil, c.protocolError("invalid payload length")
+		}
+	}
+	isControl := f.opcode >= CloseMessage
+	if isControl && (length > maxControlPayload || !f.fin || f.rsv1) {
+		return nil, c.protocolError("invalid control frame")
+	}
+	if !isControl && length > limit {
+		_ = c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""), time.Now().Add(time.Second))
+		return nil, ErrMessageTooBig
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return nil, err
+	}
+	// 不按照对端声明的长度预先分配内存 实际读到多少分配多少
+	var payload bytes.Buffer
+	if n, err := io.CopyN(&payload, c.br, length); err != nil {
+		if err == io.EOF && n < length {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	f.payload = payload.Bytes()
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+func (c *Conn) protocolError(msg string) error {
+	_ = c.WriteControl(CloseMessage, FormatCloseMessage(CloseProtocolError, msg), time.Now().Add(time.Second))
+	return errors.New("websocket: " + msg)
+}
+
+// handleControl 处理控制帧，返回的 error 不为空时连接应当结束
+func (c *Conn) handleControl(f *frame) error {
+	switch f.opcode {
+	case PingMessage:
+		return c.PingHandler(f.payload)
+	case PongMessage:
+		return c.PongHandler(f.payload)
+	case CloseMessage:
+		code, reason := CloseNoStatusReceived, ""
+		if len(f.payload) == 1 {
+			return c.protocolError("invalid close payload")
+		}
+		if len(f.payload) >= 2 {
+			code = int(binary.BigEndian.Uint16(f.payload))
+			if !validReceivedCloseCode(code) {
+				return c.protocolError(fmt.Sprintf("invalid close code %d", code))
+			}
+			reason = string(f.payload[2:])
+			if !utf8.ValidString(reason) {
+				return c.protocolError("invalid utf8 payload in close frame")
+			}
+		}
+		if err := c.CloseHandler(code, reason); err != nil {
+			return err
+		}
+		return &CloseError{Code: code, Reason: reason}
+	}
+	return c.protocolError(fmt.Sprintf("unknown opcode %d", f.opcode))
+}
+
+// validReceivedCloseCode 1005 1006 1015 只在本地使用 不能出现在关闭帧中 RFC 6455 7.4
+func validReceivedCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < CloseNormalClosure || code > CloseInternalServerErr+3: // 1012-1014 为后来注册的关闭码
+		return false
+	}
+	return code != 1004 && code != CloseNoStatusReceived && code != CloseAbnormalClosure
+}
+
+// ReadMessage 读取一条完整的消息，分片会被合并，控制帧在这里自动处理
+// 对端关闭时返回 *CloseError
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	var buf bytes.Buffer
+	compressed := false
+	for {
+		f, err := c.readFrame(c.maxMessageSize() - int64(buf.Len()))
+		if err != nil {
+			return 0, nil, err
+		}
+		if f.opcode >= CloseMessage {
+			if err := c.handleControl(f); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+		if f.opcode == continuationFrame {
+			if messageType == 0 {
+				return 0, nil, c.protocolError("continuation after final message frame")
+			}
+			if f.rsv1 {
+				return 0, nil, c.protocolError("unexpected reserved bits")
+			}
+		} else {
+			if messageType != 0 {
+				return 0, nil, c.protocolError("message start before final message frame")
+			}
+			if f.opcode != TextMessage && f.opcode != BinaryMessage {
+				return 0, nil, c.protocolError(fmt.Sprintf("unknown opcode %d", f.opcode))
+			}
+			messageType = f.opcode
+			compressed = f.rsv1
+		}
+		buf.Write(f.payload)
+		if f.fin {
+			break
+		}
+	}
+	data = buf.Bytes()
+	if compressed {
+		if data, err = c.decompress(data); err != nil {
+			return 0, nil, err
+		}
+	}
+	if messageType == TextMessage && !utf8.Valid(data) {
+		_ = c.WriteControl(CloseMessage, FormatCloseMessage(CloseInvalidFramePayloadData, ""), time.Now().Add(time.Second))
+		return 0, nil, errors.New("websocket: invalid utf8 payload")
+	}
+	return messageType, data, nil
+}
+
+// permessage-deflate 压缩时去掉的尾部，解压前需要补上
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+func (c *Conn) decompress(data []byte) ([]byte, error) {
+	reader := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer reader.Close()
+	var out bytes.Buffer
+	limit := c.maxMessageSize()
+	// 没有 BFINAL 块 读到补上的尾部后会返回 io.ErrUnexpectedEOF
+	if _, err := io.Copy(&out, io.LimitReader(reader, limit+1)); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if int64(out.Len()) > limit {
+		_ = c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""), time.Now().Add(time.Second))
+		return nil, ErrMessageTooBig
+	}
+	return out.Bytes(), nil
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+func (c *Conn) writeFrame(opcode int, rsv1 bool, payload []byte, deadline time.Time) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	// 服务端发送的帧不带掩码
+	head := make([]byte, 2, 10)
+	head[0] = finalBit | byte(opcode)
+	if rsv1 {
+		head[0] |= rsv1Bit
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		head[1] = byte(length)
+	case length <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(length))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(length))
+	}
+	// 每次都要重新设置 否则会沿用上一次控制帧的截止时间
+	if err := c.conn.SetWriteDeadline(c.deadline(deadline)); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(append(head, payload...)); err != nil {
+		// 超时等错误可能只写入了半个帧 之后的帧都无法解析 不再允许写入
+		c.closeSent = true
+		return err
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	return nil
+}
+
+// WriteMessage 发送文本或二进制消息 截止时间见 SetWriteDeadline 和 WriteTimeout
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	return c.writeMessage(messageType, data, time.Time{})
+}
+
+func (c *Conn) writeMessage(messageType int, data []byte, deadline time.Time) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data, deadline)
+	}
+	if c.compress && c.EnableWriteCompression {
+		compressed, err := compress(data)
+		if err != nil {
+			return err
+		}
+		return c.writeFrame(messageType, true, compressed, deadline)
+	}
+	return c.writeFrame(messageType, false, data, deadline)
+}
+
+// WriteText 发送文本消息
+func (c *Conn) WriteText(s string) error {
+	return c.WriteMessage(TextMessage, []byte(s))
+}
+
+// WriteControl 发送 ping pong close 控制帧
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: bad control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: invalid control frame")
+	}
+	return c.writeFrame(messageType, false, data, deadline)
+}
+
+// Ping 发送 ping，对端的 pong 由 PongHandler 处理
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data, time.Now().Add(time.Second))
+}
+
+// abort 不发送关闭帧直接关闭底层连接 用于对端已经不再读取的情况
+func (c *Conn) abort() {
+	c.closeOnce.Do(func() {
+		_ = c.conn.Close()
+	})
+}
+
+// Close 发送关闭帧后关闭底层连接
+func (c *Conn) Close(code int, reason string) error {
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	if errors.Is(err, ErrClosed) {
+		err = nil
+	}
+	c.closeOnce.Do(func() {
+		if e := c.conn.Close(); err == nil {
+			err = e
+		}
+	})
+	return err
+}
@@ -0,0 +1,387 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// echoServer 握手后把收到的消息原样发回 直到出错
+func echoServer(t *testing.T, u *Upgrader) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close(CloseNormalClosure, "")
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dial 一个最简单的客户端 返回握手响应
+func dial(t *testing.T, srv *httptest.Server, header map[string]string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", testKey)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if err = req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+// writeClientFrame 客户端发送的帧必须带掩码 masked 为 false 用来测试服务端的检查
+func writeClientFrame(t *testing.T, w io.Writer, head0 byte, payload []byte, masked bool) {
+	t.Helper()
+	buf := []byte{head0, 0}
+	switch length := len(payload); {
+	case length <= 125:
+		buf[1] = byte(length)
+	case length <= 0xffff:
+		buf[1] = 126
+		buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	default:
+		buf[1] = 127
+		buf = binary.BigEndian.AppendUint64(buf, uint64(length))
+	}
+	if masked {
+		buf[1] |= maskBit
+		mask := [4]byte{1, 2, 3, 4}
+		buf = append(buf, mask[:]...)
+		for i, b := range payload {
+			buf = append(buf, b^mask[i%4])
+		}
+	} else {
+		buf = append(buf, payload...)
+	}
+	if _, err := w.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readServerFrame 服务端的帧不带掩码 测试中的负载都小于 64K
+func readServerFrame(t *testing.T, br *bufio.Reader) (opcode int, payload []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	length := int(head[1] & 0x7f)
+	if length == 126 {
+		var b [2]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = int(binary.BigEndian.Uint16(b[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return int(head[0] & 0x0f), payload
+}
+
+func expectClose(t *testing.T, br *bufio.Reader, code int) {
+	t.Helper()
+	opcode, payload := readServerFrame(t, br)
+	if opcode != CloseMessage || len(payload) < 2 {
+		t.Fatalf("opcode = %d payload = %q, want close frame", opcode, payload)
+	}
+	if got := int(binary.BigEndian.Uint16(payload)); got != code {
+		t.Fatalf("close code = %d (%s), want %d", got, payload[2:], code)
+	}
+}
+
+func TestUpgradeHandshake(t *testing.T) {
+	srv := echoServer(t, &Upgrader{Subprotocols: []string{"chat", "superchat"}})
+	_, _, resp := dial(t, srv, map[string]string{"Sec-WebSocket-Protocol": "superchat, chat"})
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	// RFC 6455 1.3 中的例子
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	// 按服务端的优先级选择
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "chat" {
+		t.Fatalf("Sec-WebSocket-Protocol = %q", got)
+	}
+}
+
+func TestUpgradeBadHandshake(t *testing.T) {
+	cases := map[string]struct {
+		method string
+		header map[string]string
+		status int
+	}{
+		"method":     {http.MethodPost, nil, http.StatusMethodNotAllowed},
+		"connection": {http.MethodGet, map[string]string{"Connection": "keep-alive"}, http.StatusBadRequest},
+		"upgrade":    {http.MethodGet, map[string]string{"Upgrade": "h2c"}, http.StatusBadRequest},
+		"version":    {http.MethodGet, map[string]string{"Sec-WebSocket-Version": "8"}, http.StatusBadRequest},
+		"key":        {http.MethodGet, map[string]string{"Sec-WebSocket-Key": "short"}, http.StatusBadRequest},
+		"origin":     {http.MethodGet, map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+	}
+	for name, tc := range cases {
+		r := httptest.NewRequest(tc.method, "http://example.com/ws", nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", testKey)
+		for k, v := range tc.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		if _, err := (&Upgrader{}).Upgrade(w, r, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", name, w.Code, tc.status)
+		}
+	}
+}
+
+func TestReadFragmentedMessage(t *testing.T) {
+	srv := echoServer(t, &Upgrader{})
+	conn, br, _ := dial(t, srv, nil)
+	writeClientFrame(t, conn, TextMessage, []byte("hel"), true)
+	// 分片之间可以插入控制帧
+	writeClientFrame(t, conn, finalBit|PingMessage, []byte("p"), true)
+	writeClientFrame(t, conn, finalBit|continuationFrame, []byte("lo"), true)
+	if opcode, payload := readServerFrame(t, br); opcode != PongMessage || string(payload) != "p" {
+		t.Fatalf("opcode = %d payload = %q, want pong", opcode, payload)
+	}
+	if opcode, payload := readServerFrame(t, br); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatalf("opcode = %d payload = %q, want echo", opcode, payload)
+	}
+	// 正常关闭时回复同样的关闭码
+	writeClientFrame(t, conn, finalBit|CloseMessage, FormatCloseMessage(CloseGoingAway, "bye"), true)
+	expectClose(t, br, CloseGoingAway)
+}
+
+func TestReadProtocolErrors(t *testing.T) {
+	closeFrame := func(code int) []byte {
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(code))
+		return b[:]
+	}
+	cases := map[string]struct {
+		head0   byte
+		payload []byte
+		masked  bool
+	}{
+		"unmasked":            {finalBit | TextMessage, []byte("hi"), false},
+		"reserved bits":       {finalBit | 0x40 | TextMessage, []byte("hi"), true},
+		"unknown opcode":      {finalBit | 3, nil, true},
+		"continuation":        {finalBit | continuationFrame, []byte("hi"), true},
+		"fragmented control":  {PingMessage, nil, true},
+		"close code 999":      {finalBit | CloseMessage, closeFrame(999), true},
+		"close code 1005":     {finalBit | CloseMessage, closeFrame(CloseNoStatusReceived), true},
+		"close code 1006":     {finalBit | CloseMessage, closeFrame(CloseAbnormalClosure), true},
+		"close code 1015":     {finalBit | CloseMessage, closeFrame(1015), true},
+		"close code 5000":     {finalBit | CloseMessage, closeFrame(5000), true},
+		"one byte close body": {finalBit | CloseMessage, []byte{3}, true},
+	}
+	srv := echoServer(t, &Upgrader{})
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, br, _ := dial(t, srv, nil)
+			writeClientFrame(t, conn, tc.head0, tc.payload, tc.masked)
+			expectClose(t, br, CloseProtocolError)
+		})
+	}
+}
+
+func TestValidReceivedCloseCode(t *testing.T) {
+	for _, code := range []int{1000, 1001, 1003, 1007, 1011, 1014, 3000, 4999} {
+		if !validReceivedCloseCode(code) {
+			t.Errorf("%d should be valid", code)
+		}
+	}
+	for _, code := range []int{0, 999, 1004, 1005, 1006, 1015, 2999, 5000} {
+		if validReceivedCloseCode(code) {
+			t.Errorf("%d should be invalid", code)
+		}
+	}
+}
+
+func TestReadMessageTooBig(t *testing.T) {
+	srv := echoServer(t, &Upgrader{MaxMessageSize: 8})
+	conn, br, _ := dial(t, srv, nil)
+	// 分片合计超过限制
+	writeClientFrame(t, conn, TextMessage, []byte("12345"), true)
+	writeClientFrame(t, conn, finalBit|continuationFrame, []byte("67890"), true)
+	expectClose(t, br, CloseMessageTooBig)
+
+	// 默认的限制下 对端声明的超大长度也不会被信任
+	srv = echoServer(t, &Upgrader{})
+	conn, br, _ = dial(t, srv, nil)
+	head := []byte{finalBit | BinaryMessage, maskBit | 127}
+	head = binary.BigEndian.AppendUint64(head, 1<<40)
+	if _, err := conn.Write(head); err != nil {
+		t.Fatal(err)
+	}
+	expectClose(t, br, CloseMessageTooBig)
+}
+
+// pipeConn 不经过握手 直接在 net.Pipe 上创建连接 对端不读取时写入会阻塞
+func pipeConn(t *testing.T) (*Conn, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		_ = server.Close()
+		_ = client.Close()
+	})
+	return newConn(server, bufio.NewReader(server), "", false), client
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func TestWriteDeadline(t *testing.T) {
+	// SetWriteDeadline 设置的截止时间不会被 WriteMessage 清除
+	c, _ := pipeConn(t)
+	c.WriteTimeout = 0
+	if err := c.SetWriteDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteText("hello"); !isTimeout(err) {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	// 半个帧之后不再允许写入
+	if err := c.WriteText("hello"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+
+	// 没有设置截止时间时使用 WriteTimeout
+	c, _ = pipeConn(t)
+	c.WriteTimeout = 50 * time.Millisecond
+	if err := c.WriteText("hello"); !isTimeout(err) {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+}
+
+func TestHubBroadcastDropsSlowConn(t *testing.T) {
+	hub := NewHub()
+	hub.WriteTimeout = 50 * time.Millisecond
+	fast, fastClient := pipeConn(t)
+	slow, _ := pipeConn(t)
+	hub.Join("room", fast)
+	hub.Join("room", slow)
+	hub.Join("other", slow)
+	received := make(chan []byte, 1)
+	go func() {
+		// 2字节帧头 + "hi"
+		buf := make([]byte, 4)
+		_, _ = io.ReadFull(fastClient, buf)
+		received <- buf[2:]
+	}()
+	start := time.Now()
+	hub.Broadcast("room", TextMessage, []byte("hi"), nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Broadcast blocked for %v", elapsed)
+	}
+	if got := <-received; string(got) != "hi" {
+		t.Fatalf("payload = %q", got)
+	}
+	if hub.Count("room") != 1 || hub.Count("other") != 0 {
+		t.Fatalf("slow conn was not dropped: room=%d other=%d", hub.Count("room"), hub.Count("other"))
+	}
+	if err := slow.WriteText("hi"); err == nil {
+		t.Fatal("slow conn should be closed")
+	}
+}
+
+func TestHubBroadcastStalledWriter(t *testing.T) {
+	// 连接的 WriteTimeout 小于0不限制 另一个协程的写入一直阻塞并持有写锁
+	hub := NewHub()
+	hub.WriteTimeout = 50 * time.Millisecond
+	stalled, _ := pipeConn(t)
+	stalled.WriteTimeout = -1
+	hub.Join("room", stalled)
+	written := make(chan error, 1)
+	go func() { written <- stalled.WriteText("blocked") }()
+	time.Sleep(20 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		hub.Broadcast("room", TextMessage, []byte("hi"), nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Broadcast blocked by a stalled writer")
+	}
+	// 阻塞的写入被中断
+	select {
+	case err := <-written:
+		if err == nil {
+			t.Fatal("stalled write should fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stalled write was not interrupted")
+	}
+	if hub.Count("room") != 0 {
+		t.Fatalf("stalled conn was not dropped: room=%d", hub.Count("room"))
+	}
+}
+
+func TestReadDeflateMessage(t *testing.T) {
+	srv := echoServer(t, &Upgrader{EnableCompression: true, MaxMessageSize: 64})
+	conn, br, resp := dial(t, srv, map[string]string{"Sec-WebSocket-Extensions": "permessage-deflate"})
+	if !strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Fatalf("Sec-WebSocket-Extensions = %q", resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	data, err := compress([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeClientFrame(t, conn, finalBit|rsv1Bit|TextMessage, data, true)
+	if _, payload := readServerFrame(t, br); len(payload) == 0 {
+		t.Fatal("empty echo")
+	}
+	// 解压后超过限制
+	data, err = compress([]byte(strings.Repeat("a", 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeClientFrame(t, conn, finalBit|rsv1Bit|TextMessage, data, true)
+	expectClose(t, br, CloseMessageTooBig)
+}
@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultBroadcastTimeout = 5 * time.Second
+
+// Hub 按房间管理连接，用于广播
+type Hub struct {
+	mutex sync.RWMutex
+	rooms map[string]map[*Conn]struct{}
+	// WriteTimeout 广播时每个连接的写入超时 默认5s 超时的连接被认为太慢 移出所有房间并断开
+	WriteTimeout time.Duration
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]map[*Conn]struct{})}
+}
+
+func (h *Hub) Join(room string, conn *Conn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.rooms[room]; !ok {
+		h.rooms[room] = make(map[*Conn]struct{})
+	}
+	h.rooms[room][conn] = struct{}{}
+}
+
+func (h *Hub) Leave(room string, conn *Conn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.leave(room, conn)
+}
+
+func (h *Hub) leave(room string, conn *Conn) {
+	conns, ok := h.rooms[room]
+	if !ok {
+		return
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// LeaveAll 连接断开时调用，从所有房间中移除
+func (h *Hub) LeaveAll(conn *Conn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for room := range h.rooms {
+		h.leave(room, conn)
+	}
+}
+
+// Count 房间中的连接数
+func (h *Hub) Count(room string) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast 向房间中的所有连接并发发送消息 等待全部完成后返回 最多等待 WriteTimeout
+// 发送失败或者超时的连接会被移出所有房间并断开 不会拖慢其它连接
+// 连接上其它写入一直阻塞时同样在超时后断开
+// except 不为空时跳过该连接(一般是消息的发送者)
+func (h *Hub) Broadcast(room string, messageType int, data []byte, except *Conn) {
+	h.mutex.RLock()
+	conns := make([]*Conn, 0, len(h.rooms[room]))
+	for conn := range h.rooms[room] {
+		if conn != except {
+			conns = append(conns, conn)
+		}
+	}
+	h.mutex.RUnlock()
+	timeout := h.WriteTimeout
+	if timeout <= 0 {
+		timeout = defaultBroadcastTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *Conn) {
+			defer wg.Done()
+			// 其它协程的写入阻塞时会一直持有写锁 到了截止时间直接断开 让阻塞的写入返回
+			timer := time.AfterFunc(time.Until(deadline), conn.abort)
+			defer timer.Stop()
+			if err := conn.writeMessage(messageType, data, deadline); err != nil {
+				// 对端已经不再读取 关闭帧也发不出去 直接断开
+				h.LeaveAll(conn)
+				conn.abort()
+			}
+		}(conn)
+	}
+	wg.Wait()
+}
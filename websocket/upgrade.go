@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6455 中规定的固定 GUID，用于计算 Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Upgrader 握手的配置
+type Upgrader struct {
+	Subprotocols      []string                   // 服务端支持的子协议 按优先级排列
+	CheckOrigin       func(r *http.Request) bool // 为空时只允许同源请求
+	EnableCompression bool                       // 是否协商 permessage-deflate
+	MaxMessageSize    int64                      // 单条消息最大字节数 默认 32M
+	HandshakeTimeout  time.Duration
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains 判断以逗号分隔的 header 中是否含有 token，忽略大小写
+func headerContains(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	for _, server := range u.Subprotocols {
+		for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+			for _, client := range strings.Split(v, ",") {
+				if strings.TrimSpace(client) == server {
+					return server
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// 只支持不带上下文接管的 permessage-deflate，每条消息独立压缩
+func (u *Upgrader) negotiateCompression(r *http.Request) bool {
+	if !u.EnableCompression {
+		return false
+	}
+	for _, v := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(v, ",") {
+			params := strings.Split(ext, ";")
+			if strings.TrimSpace(params[0]) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func handshakeError(w http.ResponseWriter, status int, reason string) error {
+	w.Header().Set("Sec-WebSocket-Version", "13")
+	http.Error(w, http.StatusText(status), status)
+	return errors.New(ErrBadHandshake.Error() + ": " + reason)
+}
+
+// Upgrade 完成握手并接管底层连接，失败时已经向客户端写入了错误响应
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, handshakeError(w, http.StatusBadRequest, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, handshakeError(w, http.StatusForbidden, "origin not allowed")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, handshakeError(w, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key'")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, handshakeError(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.negotiateCompression(r)
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if brw.Reader.Buffered() > 0 {
+		// 客户端在握手完成前就发送了数据
+		_ = netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	sb.WriteString("Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		sb.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	for k, vs := range header {
+		if k == "Sec-Websocket-Protocol" || k == "Sec-Websocket-Extensions" {
+			continue
+		}
+		for _, v := range vs {
+			sb.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	sb.WriteString("\r\n")
+	if u.HandshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err = netConn.Write([]byte(sb.String())); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Time{})
+	}
+	conn := newConn(netConn, brw.Reader, subprotocol, compress)
+	if u.MaxMessageSize > 0 {
+		conn.MaxMessageSize = u.MaxMessageSize
+	}
+	return conn, nil
+}